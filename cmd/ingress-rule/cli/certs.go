@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var IngressRuleCertsOutput string
+var IngressRuleCertsAllNamespaces bool
+
+// certsCmd represents the certs command
+var certsCmd = &cobra.Command{
+	Use: "certs [flags]",
+	Example: "  kubectl ingress-rule certs" +
+		"\n  kubectl ingress-rule certs -A -o json" +
+		"\n  kubectl ingress-rule certs -A -o prometheus > /var/lib/node_exporter/textfile/ingress_certificates.prom",
+	Short: "Report the certificates of the tls configuration of kubernetes ingresses.",
+	Long: `Resolves the tls secret of every tls entry and prints host, secret, issuer, expiry, remaining days and whether the certificate covers the host.
+Hosts of rules without tls configuration and secrets which are missing, invalid or do not cover their hosts are flagged in the status column.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := strings.ToLower(IngressRuleCertsOutput)
+		if output != ingress_rule.CertsOutputTable && output != ingress_rule.CertsOutputJson && output != ingress_rule.CertsOutputPrometheus {
+			fmt.Println("Invalid output supplied")
+			return errors.New("invalid command line flags supplied")
+		}
+
+		options := &ingress_rule.CertsOptions{
+			AllNamespaces: IngressRuleCertsAllNamespaces,
+			Output:        output,
+		}
+		return ingress_rule.RunCerts(cmd.Context(), KubernetesConfigFlags, options)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(certsCmd)
+
+	certsCmd.Flags().StringVarP(&IngressRuleCertsOutput, "output", "o", ingress_rule.CertsOutputTable, "Output format (optional); Accepts: \"table\", \"json\", \"prometheus\"")
+	certsCmd.Flags().BoolVarP(&IngressRuleCertsAllNamespaces, "all-namespaces", "A", false, "Report the certificates of ingresses across all namespaces")
+}
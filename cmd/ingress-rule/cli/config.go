@@ -3,21 +3,55 @@ package cli
 import (
 	"fmt"
 	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
 	"github.com/spf13/pflag"
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"net/url"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type CliFlags struct {
-	Host             *string
-	Path             *string
-	PathType         *string
-	ServiceName      *string
-	PortNumber       *int
-	IngressClassName *string
-	Tls              *string
+	Host              *string
+	Path              *string
+	PathType          *string
+	ServiceName       *string
+	Port              *string
+	ResourceApiGroup  *string
+	ResourceKind      *string
+	ResourceName      *string
+	IngressClassName  *string
+	Tls               *string
+	SkipTlsValidation *bool
+	NoVerifyBackend   *bool
+	TlsSelfSigned     *bool
+	TlsValidity       *time.Duration
+	TlsKeyType        *string
+	TlsCertFile       *string
+	TlsKeyFile        *string
+	Issuer            *string
+	ClusterIssuer     *bool
+	WaitForCert       *bool
+	WaitTimeout       *time.Duration
+	Overwrite         *bool
+	AllPathsForHost   *bool
+	Filename          *string
+	InPlace           *bool
+	Target            *string
+	Gateway           *string
+	SectionName       *string
+	*UpdateFlags
+
+	flagSet *pflag.FlagSet
+}
+
+// UpdateFlags are shared by all commands modifying ingresses.
+type UpdateFlags struct {
+	DryRun       *string
+	Retries      *int
+	RetryTimeout *time.Duration
 }
 
 const COMMAND_SET = "set"
@@ -25,35 +59,128 @@ const COMMAND_DELETE = "delete"
 
 func AddOptionFlags(flagSet *pflag.FlagSet, command string) *CliFlags {
 	cf := &CliFlags{
-		Host:             stringptr(""),
-		Path:             stringptr(""),
-		PathType:         stringptr(""),
-		ServiceName:      stringptr(""),
-		IngressClassName: stringptr(""),
-		Tls:              stringptr(""),
-		PortNumber:       intptr(0),
-		//todo add support for PortName as alternative to PortNumber
+		Host:              stringptr(""),
+		Path:              stringptr(""),
+		PathType:          stringptr(""),
+		ServiceName:       stringptr(""),
+		ResourceApiGroup:  stringptr(""),
+		ResourceKind:      stringptr(""),
+		ResourceName:      stringptr(""),
+		IngressClassName:  stringptr(""),
+		Tls:               stringptr(""),
+		SkipTlsValidation: boolptr(false),
+		NoVerifyBackend:   boolptr(false),
+		TlsSelfSigned:     boolptr(false),
+		TlsValidity:       durationptr(0),
+		TlsKeyType:        stringptr(""),
+		TlsCertFile:       stringptr(""),
+		TlsKeyFile:        stringptr(""),
+		Issuer:            stringptr(""),
+		ClusterIssuer:     boolptr(false),
+		WaitForCert:       boolptr(false),
+		WaitTimeout:       durationptr(0),
+		Port:              stringptr(""),
+		Overwrite:         boolptr(false),
+		AllPathsForHost:   boolptr(false),
+		Filename:          stringptr(""),
+		InPlace:           boolptr(false),
+		Target:            stringptr(""),
+		Gateway:           stringptr(""),
+		SectionName:       stringptr(""),
+		flagSet:           flagSet,
 	}
 
+	if command == COMMAND_DELETE {
+		flagSet.StringVar(cf.Host, "host", "", "Select rules by host, an empty host selects the rule without host (optional)")
+		flagSet.StringVar(cf.Path, "path", "", "Select rules by path (optional)")
+		flagSet.StringVar(cf.PathType, "path-type", "", "Select rules by path type (optional); Accepts: \"Prefix\", \"Exact\", \"ImplementationSpecific\"")
+		flagSet.BoolVar(cf.AllPathsForHost, "all-paths-for-host", false, "Remove all paths of the host selected with --host (optional)")
+	}
 	if command == COMMAND_SET {
 		flagSet.StringVar(cf.Host, "host", "", "Set host e.g. foo.example.com, *.example.com, example.com (optional)")
 		flagSet.StringVar(cf.Path, "path", "/", "Set matching path (optional)")
 		flagSet.StringVar(cf.PathType, "path-type", "prefix", "Set matching type for path (optional); Accepts: \"Prefix\", \"Exact\", \"ImplementationSpecific\"")
 		flagSet.StringVar(cf.IngressClassName, "ingress-class", "", "Set ingressClassName when creating a new ingress, will be ignored when the ingress already exists (optional)")
-		flagSet.StringVar(cf.Tls, "tls", "", "Enable tls for rule and set tls-secret")
+		flagSet.StringVar(cf.Tls, "tls", "", "Enable tls for rule and set tls-secret, \"auto\" selects a secret whose certificate covers the host")
+		flagSet.BoolVar(cf.SkipTlsValidation, "skip-tls-validation", false, "Use the tls secret without checking that it exists and its certificate covers the host (optional)")
+		flagSet.BoolVar(cf.TlsSelfSigned, "tls-self-signed", false, "Enable tls for rule with a generated self-signed certificate for the host, stored in the secret '<host>-self-signed-tls' (optional)")
+		flagSet.DurationVar(cf.TlsValidity, "tls-validity", 90*24*time.Hour, "Validity of the self-signed certificate (optional)")
+		flagSet.StringVar(cf.TlsKeyType, "tls-key-type", string(service.KeyTypeECDSAP256), "Key type of the self-signed certificate (optional); Accepts: "+formatKeyTypes())
+		AddTlsFileFlags(flagSet, cf.TlsCertFile, cf.TlsKeyFile, "--tls")
+		flagSet.StringVar(cf.Issuer, "cert-manager-issuer", "", "Enable tls for rule with a certificate issued by this cert-manager issuer, stored in the secret '<host>-tls' (optional)")
+		flagSet.BoolVar(cf.ClusterIssuer, "cluster-issuer", false, "The cert-manager issuer is a ClusterIssuer (optional)")
+		flagSet.BoolVar(cf.WaitForCert, "wait-for-certificate", false, "Wait until the certificate issued by cert-manager is ready (optional)")
+		flagSet.DurationVar(cf.WaitTimeout, "wait-timeout", 5*time.Minute, "Maximum time to wait for the certificate (optional)")
+		flagSet.BoolVar(cf.NoVerifyBackend, "no-verify-backend", false, "Write the rule without checking that the backend service exists and defines the port (optional)")
+		flagSet.BoolVar(cf.Overwrite, "overwrite", false, "Replace the backend of an existing rule with the same host, path and path type (optional)")
+		flagSet.StringVar(cf.Gateway, "gateway", "", "Attach the HTTPRoute to the gateway, accepts \"<name>\" or \"<namespace>/<name>\"; required when creating a HTTPRoute (optional)")
+		flagSet.StringVar(cf.SectionName, "section-name", "", "Attach the HTTPRoute to the listener of the gateway (optional)")
+		flagSet.StringVar(cf.ResourceApiGroup, "resource-api-group", "", "Api group of the backend resource, empty for the core api group (optional)")
+		flagSet.StringVar(cf.ResourceKind, "resource-kind", "", "Kind of the backend resource, replaces --service and --port")
+		flagSet.StringVar(cf.ResourceName, "resource-name", "", "Name of the backend resource (must be in the same namespace as the ingress)")
 	}
 	flagSet.StringVar(cf.ServiceName, "service", "", "Name of backend service (must be in the same namespace as the ingress)")
-	flagSet.IntVar(cf.PortNumber, "port", 0, "Port number of backend service")
+	if command == COMMAND_SET {
+		flagSet.StringVar(cf.Port, "port", "", "Port number or port name of backend service, selected automatically if the service has a single port or a single port named \"http\" or \"https\" (optional)")
+	} else {
+		flagSet.StringVar(cf.Port, "port", "", "Port number or port name of backend service")
+	}
+	flagSet.StringVar(cf.Target, "target", ingress_rule.TargetIngress, "Kind of the resource managing the rules (optional); Accepts: \"ingress\", \"httproute\"")
+	flagSet.StringVarP(cf.Filename, "filename", "f", "", "Edit the ingress in a manifest file instead of the cluster, \"-\" reads from stdin (optional)")
+	flagSet.BoolVarP(cf.InPlace, "in-place", "i", false, "Write the manifest back to the file instead of stdout (optional)")
+	cf.UpdateFlags = AddUpdateFlags(flagSet)
 
 	return cf
 }
 
+// AddUpdateFlags adds the dry run and retry flags shared by all commands modifying ingresses.
+func AddUpdateFlags(flagSet *pflag.FlagSet) *UpdateFlags {
+	uf := &UpdateFlags{
+		DryRun:       stringptr(""),
+		Retries:      intptr(0),
+		RetryTimeout: durationptr(0),
+	}
+
+	flagSet.StringVar(uf.DryRun, "dry-run", "none", "Print a diff of the resulting ingress instead of persisting it (optional); Accepts: \"none\", \"client\", \"server\"")
+	flagSet.Lookup("dry-run").NoOptDefVal = "client"
+	flagSet.IntVar(uf.Retries, "retries", 5, "Number of retries when the ingress has been modified concurrently (optional)")
+	flagSet.DurationVar(uf.RetryTimeout, "retry-timeout", 30*time.Second, "Maximum time to retry when the ingress has been modified concurrently, 0 disables the timeout (optional)")
+
+	return uf
+}
+
+// CreateUpdateOptions validates the dry run and retry flags, invalid flags are reported to the user.
+func CreateUpdateOptions(flags *UpdateFlags) *ingress_rule.UpdateOptions {
+	dryRun := strings.ToLower(*flags.DryRun)
+	if dryRun != ingress_rule.DryRunNone && dryRun != ingress_rule.DryRunClient && dryRun != ingress_rule.DryRunServer {
+		fmt.Println("Invalid dry-run supplied")
+		return nil
+	}
+
+	if *flags.Retries < 0 || *flags.RetryTimeout < 0 {
+		fmt.Println("Invalid retry configuration supplied")
+		return nil
+	}
+
+	return &ingress_rule.UpdateOptions{
+		DryRun:       dryRun,
+		Retries:      *flags.Retries,
+		RetryTimeout: *flags.RetryTimeout,
+	}
+}
+
 func stringptr(val string) *string {
 	return &val
 }
+func boolptr(val bool) *bool {
+	return &val
+}
 func intptr(val int) *int {
 	return &val
 }
+func durationptr(val time.Duration) *time.Duration {
+	return &val
+}
 
 func CreateOptions(flags *CliFlags, command string, ingressName string) *ingress_rule.Options {
 	if command != COMMAND_SET && command != COMMAND_DELETE {
@@ -62,11 +189,6 @@ func CreateOptions(flags *CliFlags, command string, ingressName string) *ingress
 		return nil
 	}
 
-	if *flags.ServiceName == "" {
-		fmt.Println("No service name supplied.")
-		return nil
-	}
-
 	if ingressName == "" {
 		fmt.Printf("Error: no ingress name supplied\n")
 		return nil
@@ -75,67 +197,370 @@ func CreateOptions(flags *CliFlags, command string, ingressName string) *ingress
 	path := ""
 	pathType := networking.PathTypePrefix
 
-	if command == COMMAND_SET {
-		if *flags.Host != "" {
-			matches, err := regexp.MatchString("^([a-zA-Z0-9-_\\*]+\\.)*[a-zA-Z0-9][a-zA-Z0-9-_]+\\.[a-zA-Z]{2,11}?$", *flags.Host)
-			if err != nil {
-				fmt.Print(err)
+	updateOptions := CreateUpdateOptions(flags.UpdateFlags)
+	if updateOptions == nil {
+		return nil
+	}
+
+	if *flags.InPlace && (*flags.Filename == "" || *flags.Filename == "-") {
+		fmt.Println("Invalid combination of command line arguments: --in-place requires a manifest file")
+		return nil
+	}
+
+	target := strings.ToLower(*flags.Target)
+	if target != ingress_rule.TargetIngress && target != ingress_rule.TargetHTTPRoute {
+		fmt.Println("Invalid target supplied")
+		return nil
+	}
+
+	gatewayNamespace, gateway := "", ""
+	if target == ingress_rule.TargetHTTPRoute {
+		if *flags.Filename != "" {
+			fmt.Println("Invalid combination of command line arguments: manifest files are only supported for ingresses")
+			return nil
+		}
+		if *flags.Tls != "" || *flags.TlsSelfSigned || *flags.Issuer != "" {
+			fmt.Println("Invalid combination of command line arguments: tls of a HTTPRoute is configured on the gateway listener")
+			return nil
+		}
+		if *flags.IngressClassName != "" {
+			fmt.Println("Invalid combination of command line arguments: --ingress-class is not supported for HTTPRoutes")
+			return nil
+		}
+		if *flags.ResourceApiGroup != "" || *flags.ResourceKind != "" || *flags.ResourceName != "" {
+			fmt.Println("Invalid combination of command line arguments: resource backends are not supported for HTTPRoutes")
+			return nil
+		}
+		var ok bool
+		if gatewayNamespace, gateway, ok = parseGateway(*flags.Gateway); !ok {
+			return nil
+		}
+		if *flags.SectionName != "" && gateway == "" {
+			fmt.Println("Invalid combination of command line arguments: --section-name requires a gateway")
+			return nil
+		}
+	} else if *flags.Gateway != "" || *flags.SectionName != "" {
+		fmt.Println("Invalid combination of command line arguments: --gateway and --section-name require --target httproute")
+		return nil
+	}
+
+	portNumber, portName, ok := parsePort(*flags.Port)
+	if !ok {
+		fmt.Println("Invalid port supplied")
+		return nil
+	}
+	backend := &ingress_rule.BackendOptions{
+		ServiceName: *flags.ServiceName,
+		PortNumber:  portNumber,
+		PortName:    portName,
+	}
+
+	if *flags.Host != "" && !validateHost(*flags.Host) {
+		return nil
+	}
+
+	if command == COMMAND_DELETE {
+		if *flags.Path != "" {
+			if path, ok = parsePath(*flags.Path); !ok {
 				return nil
 			}
-			if !matches {
-				fmt.Println("Invalid host supplied")
+		}
+
+		pathType = ""
+		if *flags.PathType != "" {
+			if pathType, ok = parsePathType(*flags.PathType); !ok {
 				return nil
 			}
 		}
 
-		if *flags.PortNumber < 0 || *flags.PortNumber >= 1<<16 {
-			fmt.Println("Invalid port supplied")
+		hostSelected := flags.flagSet.Changed("host")
+		otherSelectorSupplied := path != "" || pathType != "" || *flags.ServiceName != "" || portNumber != 0 || portName != ""
+
+		if *flags.AllPathsForHost && !hostSelected {
+			fmt.Println("Invalid combination of command line arguments: --all-paths-for-host requires a host")
 			return nil
 		}
+		if *flags.AllPathsForHost && otherSelectorSupplied {
+			fmt.Println("Invalid combination of command line arguments: --all-paths-for-host can not be combined with other selectors")
+			return nil
+		}
+		if hostSelected && !otherSelectorSupplied && !*flags.AllPathsForHost {
+			fmt.Println("Selecting rules only by host removes all paths of the host; use --all-paths-for-host to confirm")
+			return nil
+		}
+	}
 
-		if *flags.PortNumber == 0 {
-			fmt.Println("No port supplied")
+	if command == COMMAND_SET {
+		// the port of the service is selected automatically unless there is no cluster access
+		portRequired := *flags.Filename != ""
+		if backend = createBackendOptions(flags.ServiceName, flags.Port, flags.ResourceApiGroup, flags.ResourceKind, flags.ResourceName, portRequired); backend == nil {
 			return nil
 		}
 
-		pathUri, err := url.ParseRequestURI(*flags.Path)
-		if err != nil {
-			fmt.Println("Invalid path supplied")
+		if path, ok = parsePath(*flags.Path); !ok {
 			return nil
 		}
-		path = pathUri.Path
 
-		switch strings.ToLower(*flags.PathType) {
-		case "exact":
-			pathType = networking.PathTypeExact
-			break
-		case "prefix":
-			pathType = networking.PathTypePrefix
-			break
-		case "implementationspecific":
-			pathType = networking.PathTypeImplementationSpecific
-			break
-		default:
-			fmt.Println("Invalid path-type supplied")
+		if pathType, ok = parsePathType(*flags.PathType); !ok {
 			return nil
 		}
 
-		if *flags.Tls != "" && *flags.Host == "" {
+		if *flags.TlsSelfSigned && *flags.Tls != "" {
+			fmt.Println("Invalid combination of command line arguments: --tls and --tls-self-signed can not be combined")
+			return nil
+		}
+		if !*flags.TlsSelfSigned && (flags.flagSet.Changed("tls-validity") || flags.flagSet.Changed("tls-key-type")) {
+			fmt.Println("Invalid combination of command line arguments: --tls-validity and --tls-key-type require --tls-self-signed")
+			return nil
+		}
+		if !validateTlsFiles(*flags.TlsCertFile, *flags.TlsKeyFile, *flags.Tls, "--tls") {
+			return nil
+		}
+		if (*flags.TlsSelfSigned || *flags.TlsCertFile != "") && *flags.Filename != "" {
+			fmt.Println("Invalid combination of command line arguments: creating tls secrets requires cluster access and can not be used with manifest files")
+			return nil
+		}
+		if *flags.TlsValidity <= 0 {
+			fmt.Println("Invalid tls-validity supplied")
+			return nil
+		}
+		if !validKeyType(*flags.TlsKeyType) {
+			fmt.Println("Invalid tls-key-type supplied")
+			return nil
+		}
+
+		if !validateCertManagerFlags(flags) {
+			return nil
+		}
+
+		if (*flags.Tls != "" || *flags.TlsSelfSigned || *flags.Issuer != "") && *flags.Host == "" {
 			fmt.Println("Invalid combination of command line arguments: tls configuration requires a hostname")
 			return nil
 		}
+		if *flags.Tls == ingress_rule.TlsAuto && *flags.Filename != "" {
+			fmt.Println("Invalid combination of command line arguments: --tls auto requires cluster access and can not be used with manifest files")
+			return nil
+		}
+	}
+
+	options := &ingress_rule.Options{
+		IngressName:       ingressName,
+		IngressClassName:  *flags.IngressClassName,
+		Host:              *flags.Host,
+		MatchHost:         flags.flagSet.Changed("host"),
+		Path:              path,
+		Delete:            strings.ToLower(command) == COMMAND_DELETE,
+		Set:               strings.ToLower(command) == COMMAND_SET,
+		PathType:          pathType,
+		TlsSecret:         *flags.Tls,
+		SkipTlsValidation: *flags.SkipTlsValidation,
+		NoVerifyBackend:   *flags.NoVerifyBackend,
+		Overwrite:         *flags.Overwrite,
+		Filename:          *flags.Filename,
+		InPlace:           *flags.InPlace,
+		Target:            target,
+		BackendOptions:    *backend,
+		GatewayOptions: ingress_rule.GatewayOptions{
+			Gateway:          gateway,
+			GatewayNamespace: gatewayNamespace,
+			SectionName:      *flags.SectionName,
+		},
+		TlsSecretOptions: ingress_rule.TlsSecretOptions{
+			SelfSigned: *flags.TlsSelfSigned,
+			Validity:   *flags.TlsValidity,
+			KeyType:    service.KeyType(strings.ToLower(*flags.TlsKeyType)),
+			CertFile:   *flags.TlsCertFile,
+			KeyFile:    *flags.TlsKeyFile,
+		},
+		CertManagerOptions: ingress_rule.CertManagerOptions{
+			Issuer:             *flags.Issuer,
+			ClusterIssuer:      *flags.ClusterIssuer,
+			WaitForCertificate: *flags.WaitForCert,
+			WaitTimeout:        *flags.WaitTimeout,
+		},
+		UpdateOptions: *updateOptions,
+	}
+
+	if options.Delete && options.RuleSelector().IsEmpty() {
+		fmt.Println("No selector supplied; select rules by --host, --path, --path-type, --service or --port")
+		return nil
+	}
+	return options
+}
+
+// validateCertManagerFlags checks that the cert-manager flags are neither combined with other tls sources nor supplied without issuer and reports invalid combinations to the user.
+func validateCertManagerFlags(flags *CliFlags) bool {
+	if *flags.Issuer == "" {
+		if *flags.ClusterIssuer || *flags.WaitForCert || flags.flagSet.Changed("wait-timeout") {
+			fmt.Println("Invalid combination of command line arguments: --cluster-issuer, --wait-for-certificate and --wait-timeout require --cert-manager-issuer")
+			return false
+		}
+		return true
+	}
+
+	if len(validation.IsDNS1123Subdomain(*flags.Issuer)) != 0 {
+		fmt.Println("Invalid cert-manager-issuer supplied")
+		return false
+	}
+	if *flags.Tls != "" || *flags.TlsSelfSigned || *flags.TlsCertFile != "" {
+		fmt.Println("Invalid combination of command line arguments: --cert-manager-issuer can not be combined with --tls, --tls-self-signed or --tls-cert-file")
+		return false
+	}
+	if *flags.WaitForCert && (*flags.Filename != "" || strings.ToLower(*flags.DryRun) != ingress_rule.DryRunNone) {
+		fmt.Println("Invalid combination of command line arguments: --wait-for-certificate can neither be used with manifest files nor with --dry-run")
+		return false
+	}
+	if *flags.WaitTimeout <= 0 {
+		fmt.Println("Invalid wait-timeout supplied")
+		return false
+	}
+	return true
+}
+
+// AddTlsFileFlags adds the flags creating the tls secret selected by the secret flag from certificate and key files.
+func AddTlsFileFlags(flagSet *pflag.FlagSet, certFile *string, keyFile *string, secretFlag string) {
+	flagSet.StringVar(certFile, "tls-cert-file", "", "Create or update the tls secret selected by "+secretFlag+" from the PEM encoded certificate file (optional)")
+	flagSet.StringVar(keyFile, "tls-key-file", "", "PEM encoded private key file of the certificate supplied with --tls-cert-file")
+}
+
+// validateTlsFiles checks that certificate and key files are supplied together with the name of the secret to create and reports invalid combinations to the user.
+func validateTlsFiles(certFile string, keyFile string, secret string, secretFlag string) bool {
+	if (certFile == "") != (keyFile == "") {
+		fmt.Println("Invalid combination of command line arguments: --tls-cert-file and --tls-key-file have to be supplied together")
+		return false
+	}
+	if certFile != "" && (secret == "" || secret == ingress_rule.TlsAuto) {
+		fmt.Printf("Invalid combination of command line arguments: --tls-cert-file requires the name of the secret to create with %s\n", secretFlag)
+		return false
+	}
+	return true
+}
+
+// validKeyType checks case-insensitively if the key type of self-signed certificates is supported.
+func validKeyType(keyType string) bool {
+	for _, supported := range service.KeyTypes {
+		if strings.ToLower(keyType) == string(supported) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatKeyTypes returns the quoted supported key types for the flag usage.
+func formatKeyTypes() string {
+	var keyTypes []string
+	for _, keyType := range service.KeyTypes {
+		keyTypes = append(keyTypes, fmt.Sprintf("%q", keyType))
+	}
+	return strings.Join(keyTypes, ", ")
+}
+
+// createBackendOptions validates the backend flags, either a service with port or a resource has to be supplied.
+// Without portRequired the port of a service may be omitted.
+// Invalid backends are reported to the user.
+func createBackendOptions(serviceName *string, port *string, resourceApiGroup *string, resourceKind *string, resourceName *string, portRequired bool) *ingress_rule.BackendOptions {
+	serviceSupplied := *serviceName != "" || *port != ""
+	resourceSupplied := *resourceApiGroup != "" || *resourceKind != "" || *resourceName != ""
+	if serviceSupplied && resourceSupplied {
+		fmt.Println("Invalid combination of command line arguments: a backend is either a service or a resource")
+		return nil
+	}
+
+	if resourceSupplied {
+		if *resourceKind == "" || *resourceName == "" {
+			fmt.Println("Invalid combination of command line arguments: a resource backend requires --resource-kind and --resource-name")
+			return nil
+		}
+		if *resourceApiGroup != "" && len(validation.IsDNS1123Subdomain(*resourceApiGroup)) != 0 {
+			fmt.Println("Invalid resource api group supplied")
+			return nil
+		}
+		return &ingress_rule.BackendOptions{
+			ResourceApiGroup: *resourceApiGroup,
+			ResourceKind:     *resourceKind,
+			ResourceName:     *resourceName,
+		}
+	}
+
+	if *serviceName == "" {
+		fmt.Println("No service name supplied; use --service and --port or --resource-kind and --resource-name")
+		return nil
+	}
+	portNumber, portName, ok := parsePort(*port)
+	if !ok {
+		fmt.Println("Invalid port supplied")
+		return nil
+	}
+	if portNumber == 0 && portName == "" && portRequired {
+		fmt.Println("No port supplied")
+		return nil
+	}
+	return &ingress_rule.BackendOptions{
+		ServiceName: *serviceName,
+		PortNumber:  portNumber,
+		PortName:    portName,
+	}
+}
+
+// validateHost checks that the host is a valid hostname (wildcards are allowed) and reports invalid hosts to the user.
+func validateHost(host string) bool {
+	if !ingress_rule.ValidHost(host) {
+		fmt.Println("Invalid host supplied")
+		return false
+	}
+	return true
+}
+
+// parsePath returns the path component of the supplied path and reports invalid paths to the user.
+func parsePath(path string) (string, bool) {
+	pathUri, err := url.ParseRequestURI(path)
+	if err != nil {
+		fmt.Println("Invalid path supplied")
+		return "", false
+	}
+	return pathUri.Path, true
+}
+
+// parsePathType parses the path type case-insensitively and reports invalid path types to the user.
+func parsePathType(pathType string) (networking.PathType, bool) {
+	parsed, err := ingress_rule.ParsePathType(pathType)
+	if err != nil {
+		fmt.Println("Invalid path-type supplied")
+		return "", false
+	}
+	return parsed, true
+}
+
+// parseGateway splits the gateway into namespace and name and reports invalid gateways to the user.
+// The namespace is optional, an empty gateway is valid and results in neither namespace nor name.
+func parseGateway(gateway string) (namespace string, name string, ok bool) {
+	name = gateway
+	if i := strings.Index(gateway, "/"); i != -1 {
+		namespace, name = gateway[:i], gateway[i+1:]
+	}
+	if gateway != "" && (len(validation.IsDNS1123Subdomain(name)) != 0 || (namespace != "" && len(validation.IsDNS1123Label(namespace)) != 0)) {
+		fmt.Println("Invalid gateway supplied")
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// parsePort interprets the supplied port either as port number or as port name.
+// An empty port is valid and results in neither a port number nor a port name.
+func parsePort(port string) (number int32, name string, ok bool) {
+	if port == "" {
+		return 0, "", true
+	}
+
+	if n, err := strconv.Atoi(port); err == nil {
+		if n <= 0 || n >= 1<<16 {
+			return 0, "", false
+		}
+		return int32(n), "", true
 	}
 
-	return &ingress_rule.Options{
-		IngressName:      ingressName,
-		IngressClassName: *flags.IngressClassName,
-		Host:             *flags.Host,
-		Path:             path,
-		Delete:           strings.ToLower(command) == COMMAND_DELETE,
-		Set:              strings.ToLower(command) == COMMAND_SET,
-		PathType:         pathType,
-		ServiceName:      *flags.ServiceName,
-		PortNumber:       int32(*flags.PortNumber),
-		TlsSecret:        *flags.Tls,
+	if len(validation.IsValidPortName(port)) != 0 {
+		return 0, "", false
 	}
+	return 0, port, true
 }
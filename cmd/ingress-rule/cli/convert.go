@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var IngressRuleConvertTo string
+var IngressRuleConvertGateway string
+var IngressRuleConvertSectionName string
+var IngressRuleConvertApply bool
+var IngressRuleConvertUpdateFlags *UpdateFlags
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use: "convert <ingress-name> --to httproute [flags]",
+	Example: "  kubectl ingress-rule convert my-ingress --to httproute --gateway infra/my-gateway > routes.yaml" +
+		"\n  kubectl ingress-rule convert my-ingress --to httproute --gateway infra/my-gateway --section-name https --apply",
+	Short: "Convert an ingress to Gateway API HTTPRoutes.",
+	Long: `Translates the rules, path types, tls configuration and default backend of an ingress to a HTTPRoute per host.
+The gateway listeners referencing the tls secrets are written as yaml snippet to merge into the gateway.
+Constructs which can not be expressed by HTTPRoutes, like ImplementationSpecific paths or controller annotations, are reported on stderr.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		// validate ingress-name arg
+		if len(args) < 1 {
+			return errors.New("no ingress name was specified")
+		} else if len(args) > 1 {
+			return errors.New("invalid number of command line arguments; only a ingress name is expected")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if strings.ToLower(IngressRuleConvertTo) != ingress_rule.TargetHTTPRoute {
+			fmt.Println("Invalid conversion target supplied")
+			return errors.New("invalid command line flags supplied")
+		}
+
+		gatewayNamespace, gateway, ok := parseGateway(IngressRuleConvertGateway)
+		if !ok {
+			return errors.New("invalid command line flags supplied")
+		}
+		if IngressRuleConvertSectionName != "" && gateway == "" {
+			fmt.Println("Invalid combination of command line arguments: --section-name requires a gateway")
+			return errors.New("invalid command line flags supplied")
+		}
+
+		updateOptions := CreateUpdateOptions(IngressRuleConvertUpdateFlags)
+		if updateOptions == nil {
+			return errors.New("invalid command line flags supplied")
+		}
+		if updateOptions.DryRun != ingress_rule.DryRunNone && !IngressRuleConvertApply {
+			fmt.Println("Invalid combination of command line arguments: --dry-run requires --apply")
+			return errors.New("invalid command line flags supplied")
+		}
+
+		return ingress_rule.RunConvert(cmd.Context(), KubernetesConfigFlags, &ingress_rule.ConvertOptions{
+			IngressName: args[0],
+			To:          ingress_rule.TargetHTTPRoute,
+			Apply:       IngressRuleConvertApply,
+			GatewayOptions: ingress_rule.GatewayOptions{
+				Gateway:          gateway,
+				GatewayNamespace: gatewayNamespace,
+				SectionName:      IngressRuleConvertSectionName,
+			},
+			UpdateOptions: *updateOptions,
+		})
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVar(&IngressRuleConvertTo, "to", "", "Kind of the resources to convert to; Accepts: \"httproute\"")
+	convertCmd.Flags().StringVar(&IngressRuleConvertGateway, "gateway", "", "Attach the HTTPRoutes to the gateway, accepts \"<name>\" or \"<namespace>/<name>\" (optional)")
+	convertCmd.Flags().StringVar(&IngressRuleConvertSectionName, "section-name", "", "Attach the HTTPRoutes to the listener of the gateway (optional)")
+	convertCmd.Flags().BoolVar(&IngressRuleConvertApply, "apply", false, "Create or update the HTTPRoutes in the cluster instead of writing them to stdout (optional)")
+	IngressRuleConvertUpdateFlags = AddUpdateFlags(convertCmd.Flags())
+
+	convertCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"errors"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DefaultBackendFlags are the flags of the set-default and unset-default commands.
+type DefaultBackendFlags struct {
+	ServiceName      *string
+	Port             *string
+	ResourceApiGroup *string
+	ResourceKind     *string
+	ResourceName     *string
+	IngressClassName *string
+	*UpdateFlags
+}
+
+var IngressRuleSetDefaultFlags *DefaultBackendFlags
+var IngressRuleUnsetDefaultFlags *DefaultBackendFlags
+
+// setDefaultCmd represents the set-default command
+var setDefaultCmd = &cobra.Command{
+	Use: "set-default <ingress-name> [flags]",
+	Example: "  kubectl ingress-rule set-default my-ingress --service foo --port 80" +
+		"\n  kubectl ingress-rule set-default my-ingress --resource-api-group k8s.example.com --resource-kind StorageBucket --resource-name static-assets",
+	Short: "Set the default backend of a kubernetes ingress. If the ingress does not exist a new ingress will be created.",
+	Long:  `Sets the default backend of an ingress, which serves all requests not matching any rule. Either a service or a resource can be used as backend. An existing default backend will be replaced.`,
+	Args:  ingressNameArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := CreateDefaultBackendOptions(IngressRuleSetDefaultFlags, false, args[0])
+		if options == nil {
+			return errors.New("invalid command line flags supplied")
+		}
+
+		return ingress_rule.RunDefaultBackend(cmd.Context(), KubernetesConfigFlags, options)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// unsetDefaultCmd represents the unset-default command
+var unsetDefaultCmd = &cobra.Command{
+	Use:     "unset-default <ingress-name> [flags]",
+	Example: "  kubectl ingress-rule unset-default my-ingress",
+	Short:   "Remove the default backend of a kubernetes ingress. Deletes the ingress if there are no rules left.",
+	Long:    `Removes the default backend of an ingress. Deletes the ingress if there are no rules left.`,
+	Args:    ingressNameArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := CreateDefaultBackendOptions(IngressRuleUnsetDefaultFlags, true, args[0])
+		if options == nil {
+			return errors.New("invalid command line flags supplied")
+		}
+
+		return ingress_rule.RunDefaultBackend(cmd.Context(), KubernetesConfigFlags, options)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(setDefaultCmd)
+	rootCmd.AddCommand(unsetDefaultCmd)
+
+	IngressRuleSetDefaultFlags = AddDefaultBackendFlags(setDefaultCmd.Flags(), false)
+	IngressRuleUnsetDefaultFlags = AddDefaultBackendFlags(unsetDefaultCmd.Flags(), true)
+}
+
+// ingressNameArgs validates that exactly one ingress name is supplied.
+func ingressNameArgs(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New("no ingress name was specified")
+	} else if len(args) > 1 {
+		return errors.New("invalid number of command line arguments; only a ingress name is expected")
+	}
+	return nil
+}
+
+func AddDefaultBackendFlags(flagSet *pflag.FlagSet, unset bool) *DefaultBackendFlags {
+	df := &DefaultBackendFlags{
+		ServiceName:      stringptr(""),
+		Port:             stringptr(""),
+		ResourceApiGroup: stringptr(""),
+		ResourceKind:     stringptr(""),
+		ResourceName:     stringptr(""),
+		IngressClassName: stringptr(""),
+	}
+
+	if !unset {
+		flagSet.StringVar(df.ServiceName, "service", "", "Name of the default backend service (must be in the same namespace as the ingress)")
+		flagSet.StringVar(df.Port, "port", "", "Port number or port name of the default backend service")
+		flagSet.StringVar(df.ResourceApiGroup, "resource-api-group", "", "Api group of the default backend resource, empty for the core api group (optional)")
+		flagSet.StringVar(df.ResourceKind, "resource-kind", "", "Kind of the default backend resource")
+		flagSet.StringVar(df.ResourceName, "resource-name", "", "Name of the default backend resource (must be in the same namespace as the ingress)")
+		flagSet.StringVar(df.IngressClassName, "ingress-class", "", "Set ingressClassName when creating a new ingress, will be ignored when the ingress already exists (optional)")
+	}
+	df.UpdateFlags = AddUpdateFlags(flagSet)
+
+	return df
+}
+
+// CreateDefaultBackendOptions validates the flags, either a service with port or a resource has to be supplied for set-default.
+func CreateDefaultBackendOptions(flags *DefaultBackendFlags, unset bool, ingressName string) *ingress_rule.DefaultBackendOptions {
+	updateOptions := CreateUpdateOptions(flags.UpdateFlags)
+	if updateOptions == nil {
+		return nil
+	}
+
+	options := &ingress_rule.DefaultBackendOptions{
+		IngressName:      ingressName,
+		IngressClassName: *flags.IngressClassName,
+		Unset:            unset,
+		UpdateOptions:    *updateOptions,
+	}
+	if unset {
+		return options
+	}
+
+	backend := createBackendOptions(flags.ServiceName, flags.Port, flags.ResourceApiGroup, flags.ResourceKind, flags.ResourceName, true)
+	if backend == nil {
+		return nil
+	}
+	options.BackendOptions = *backend
+	return options
+}
@@ -12,9 +12,14 @@ var IngressRuleDeleteConfigFlags *CliFlags
 var deleteCmd = &cobra.Command{
 	Use: "delete <ingress-name> [flags]",
 	Example: "  kubectl ingress-rule delete my-ingress --service foo" +
-		"\n  kubectl ingress-rule delete my-ingress --service foo --port 80",
+		"\n  kubectl ingress-rule delete my-ingress --service foo --port 80" +
+		"\n  kubectl ingress-rule delete my-ingress --host foo.com --path /api --path-type exact" +
+		"\n  kubectl ingress-rule delete my-ingress --host foo.com --all-paths-for-host" +
+		"\n  kubectl ingress-rule delete my-ingress --service foo --dry-run=server" +
+		"\n  kubectl ingress-rule delete my-ingress --service foo -f manifest.yaml --in-place" +
+		"\n  kubectl ingress-rule delete my-route --target httproute --service foo",
 	Short: "Remove kubernetes ingress rules via command line. Deletes the ingress if there are no rules left.",
-	Long:  `Deletes a backend rule from an ingress. Deletes the ingress if there are no rules left. Supports removal by service name or a combination of service name and port number. When deleting the last rule for a host the tls entry will also be removed.`,
+	Long:  `Deletes a backend rule from an ingress. Deletes the ingress if there are neither rules nor a default backend left. Rules are selected by host, path, path type, service name and port; all supplied selectors have to match. When deleting the last rule for a host the tls entry will also be removed.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		// validate ingress-name arg
 		if len(args) < 1 {
@@ -44,6 +49,4 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 
 	IngressRuleDeleteConfigFlags = AddOptionFlags(deleteCmd.Flags(), COMMAND_DELETE)
-
-	deleteCmd.MarkFlagRequired("service")
 }
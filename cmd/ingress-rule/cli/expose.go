@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strconv"
+	"strings"
+)
+
+// ExposeFlags are the flags of the expose command.
+type ExposeFlags struct {
+	Host              *string
+	Path              *string
+	PathType          *string
+	Port              *string
+	TargetPort        *string
+	ServiceName       *string
+	IngressClassName  *string
+	Tls               *string
+	SkipTlsValidation *bool
+	*UpdateFlags
+}
+
+var IngressRuleExposeFlags *ExposeFlags
+
+// workloadKinds maps the accepted workload kinds and their short names to the workloads of the service package.
+var workloadKinds = map[string]string{
+	"deployment":   service.WorkloadDeployment,
+	"deployments":  service.WorkloadDeployment,
+	"deploy":       service.WorkloadDeployment,
+	"statefulset":  service.WorkloadStatefulSet,
+	"statefulsets": service.WorkloadStatefulSet,
+	"sts":          service.WorkloadStatefulSet,
+	"daemonset":    service.WorkloadDaemonSet,
+	"daemonsets":   service.WorkloadDaemonSet,
+	"ds":           service.WorkloadDaemonSet,
+}
+
+// exposeCmd represents the expose command
+var exposeCmd = &cobra.Command{
+	Use: "expose <kind>/<name> [ingress-name] [flags]",
+	Example: "  kubectl ingress-rule expose deployment/foo --host foo.com" +
+		"\n  kubectl ingress-rule expose deployment/foo my-ingress --host foo.com --path /api --port 80 --target-port http" +
+		"\n  kubectl ingress-rule expose statefulset/bar my-ingress --host bar.com --tls my-tls-secret --dry-run",
+	Short: "Expose a workload by a service and an ingress rule.",
+	Long: `Creates a ClusterIP service selecting the pods of a deployment, stateful set or daemon set (like 'kubectl expose') unless the service already exists and adds a rule routing to it.
+The service is named after the workload and the ingress defaults to the name of the service. A service created by the command is deleted again if the rule can not be added.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("no workload was specified")
+		} else if len(args) > 2 {
+			return errors.New("invalid number of command line arguments; only a workload and a ingress name are expected")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ingressName := ""
+		if len(args) == 2 {
+			ingressName = args[1]
+		}
+
+		options := CreateExposeOptions(IngressRuleExposeFlags, args[0], ingressName)
+		if options == nil {
+			return errors.New("invalid command line flags supplied")
+		}
+
+		return ingress_rule.RunExpose(cmd.Context(), KubernetesConfigFlags, options)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(exposeCmd)
+
+	IngressRuleExposeFlags = AddExposeFlags(exposeCmd.Flags())
+}
+
+func AddExposeFlags(flagSet *pflag.FlagSet) *ExposeFlags {
+	ef := &ExposeFlags{
+		Host:              stringptr(""),
+		Path:              stringptr(""),
+		PathType:          stringptr(""),
+		Port:              stringptr(""),
+		TargetPort:        stringptr(""),
+		ServiceName:       stringptr(""),
+		IngressClassName:  stringptr(""),
+		Tls:               stringptr(""),
+		SkipTlsValidation: boolptr(false),
+	}
+
+	flagSet.StringVar(ef.Host, "host", "", "Set host e.g. foo.example.com, *.example.com, example.com (optional)")
+	flagSet.StringVar(ef.Path, "path", "/", "Set matching path (optional)")
+	flagSet.StringVar(ef.PathType, "path-type", "prefix", "Set matching type for path (optional); Accepts: \"Prefix\", \"Exact\", \"ImplementationSpecific\"")
+	flagSet.StringVar(ef.Port, "port", "", "Port number of the service, a new service mirrors the container ports of the workload without port; selected automatically if the service has a single port or a single port named \"http\" or \"https\" (optional)")
+	flagSet.StringVar(ef.TargetPort, "target-port", "", "Container port number or name targeted by a new service, defaults to the container port matching --port or the only container port (optional)")
+	flagSet.StringVar(ef.ServiceName, "service", "", "Name of the service, defaults to the name of the workload (optional)")
+	flagSet.StringVar(ef.IngressClassName, "ingress-class", "", "Set ingressClassName when creating a new ingress, will be ignored when the ingress already exists (optional)")
+	flagSet.StringVar(ef.Tls, "tls", "", "Enable tls for rule and set tls-secret (optional)")
+	flagSet.BoolVar(ef.SkipTlsValidation, "skip-tls-validation", false, "Use the tls secret without checking that it exists and its certificate covers the host (optional)")
+	ef.UpdateFlags = AddUpdateFlags(flagSet)
+
+	return ef
+}
+
+// CreateExposeOptions validates the workload and the flags, invalid arguments are reported to the user.
+func CreateExposeOptions(flags *ExposeFlags, workload string, ingressName string) *ingress_rule.ExposeOptions {
+	updateOptions := CreateUpdateOptions(flags.UpdateFlags)
+	if updateOptions == nil {
+		return nil
+	}
+
+	kind, name, ok := parseWorkload(workload)
+	if !ok {
+		return nil
+	}
+
+	serviceName := *flags.ServiceName
+	if serviceName == "" {
+		serviceName = name
+	}
+	if len(validation.IsDNS1035Label(serviceName)) != 0 {
+		fmt.Println("Invalid service name supplied; a service name has to be a DNS-1035 label, use --service to select another name")
+		return nil
+	}
+	if ingressName == "" {
+		ingressName = serviceName
+	}
+
+	var port int32
+	if *flags.Port != "" {
+		n, err := strconv.Atoi(*flags.Port)
+		if err != nil || n <= 0 || n >= 1<<16 {
+			fmt.Println("Invalid port supplied; the port of the service has to be a number")
+			return nil
+		}
+		port = int32(n)
+	}
+	if *flags.TargetPort != "" {
+		if port == 0 {
+			fmt.Println("Invalid combination of command line arguments: --target-port requires --port")
+			return nil
+		}
+		if _, _, ok := parsePort(*flags.TargetPort); !ok {
+			fmt.Println("Invalid target-port supplied")
+			return nil
+		}
+	}
+
+	if *flags.Host != "" && !validateHost(*flags.Host) {
+		return nil
+	}
+	path, ok := parsePath(*flags.Path)
+	if !ok {
+		return nil
+	}
+	pathType, ok := parsePathType(*flags.PathType)
+	if !ok {
+		return nil
+	}
+	if *flags.Tls != "" && *flags.Host == "" {
+		fmt.Println("Invalid combination of command line arguments: tls configuration requires a hostname")
+		return nil
+	}
+	if *flags.Tls != "" && len(validation.IsDNS1123Subdomain(*flags.Tls)) != 0 {
+		fmt.Println("Invalid tls secret supplied")
+		return nil
+	}
+
+	return &ingress_rule.ExposeOptions{
+		WorkloadKind: kind,
+		WorkloadName: name,
+		TargetPort:   *flags.TargetPort,
+		Options: ingress_rule.Options{
+			IngressName:       ingressName,
+			IngressClassName:  *flags.IngressClassName,
+			Host:              *flags.Host,
+			Path:              path,
+			Set:               true,
+			PathType:          pathType,
+			TlsSecret:         *flags.Tls,
+			SkipTlsValidation: *flags.SkipTlsValidation,
+			Target:            ingress_rule.TargetIngress,
+			BackendOptions: ingress_rule.BackendOptions{
+				ServiceName: serviceName,
+				PortNumber:  port,
+			},
+			UpdateOptions: *updateOptions,
+		},
+	}
+}
+
+// parseWorkload splits the workload into kind and name and reports unsupported kinds and invalid names to the user.
+func parseWorkload(workload string) (kind string, name string, ok bool) {
+	i := strings.Index(workload, "/")
+	if i == -1 {
+		fmt.Println("Invalid workload supplied; expected <kind>/<name>, e.g. deployment/foo")
+		return "", "", false
+	}
+
+	kind, ok = workloadKinds[strings.ToLower(workload[:i])]
+	if !ok {
+		fmt.Println("Invalid workload kind supplied; Accepts: \"deployment\", \"statefulset\", \"daemonset\"")
+		return "", "", false
+	}
+	name = workload[i+1:]
+	if len(validation.IsDNS1123Subdomain(name)) != 0 {
+		fmt.Println("Invalid workload name supplied")
+		return "", "", false
+	}
+	return kind, name, true
+}
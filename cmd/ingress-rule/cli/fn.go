@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// fnCmd represents the fn command
+var fnCmd = &cobra.Command{
+	Use: "fn",
+	Example: "  kubectl ingress-rule fn < resource-list.yaml" +
+		"\n  kustomize build --enable-alpha-plugins --enable-exec .",
+	Short: "Run as KRM function applying rule operations to the ingresses of a ResourceList.",
+	Long: `Reads a ResourceList on stdin, applies the rule operations of its functionConfig to the matching ingresses and writes the ResourceList to stdout.
+Ingresses are created if they do not exist and removed if there are no rules left. Rules which already exist or are already removed are skipped.
+
+Example functionConfig:
+
+  apiVersion: ingress-rule.pragaonj.github.io/v1alpha1
+  kind: IngressRuleConfig
+  metadata:
+    name: dev-rules
+  spec:
+    operations:
+    - action: set
+      ingress: my-ingress
+      host: foo.com
+      service: foo
+      port: 80
+      tls: my-tls-secret
+    - action: delete
+      ingress: my-ingress
+      service: bar`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ingress_rule.RunFunction(os.Stdin, os.Stdout); err != nil {
+			// stdout is reserved for the ResourceList
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(fnCmd)
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"errors"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+	"strings"
+)
+
+var IngressRuleListPrintFlags *genericclioptions.PrintFlags
+var IngressRuleListAllNamespaces bool
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:     "list [ingress-name] [flags]",
+	Aliases: []string{"get"},
+	Example: "  kubectl ingress-rule list" +
+		"\n  kubectl ingress-rule list my-ingress -o wide" +
+		"\n  kubectl ingress-rule list -A -o yaml",
+	Short: "List kubernetes ingress rules.",
+	Long:  `Lists the rules of all ingresses in the namespace or of a single ingress. Every backend path is printed as one row containing ingress, host, path, path type, service, port and tls secret.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("invalid number of command line arguments; only a ingress name is expected")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := &ingress_rule.ListOptions{
+			AllNamespaces: IngressRuleListAllNamespaces,
+			Wide:          *IngressRuleListPrintFlags.OutputFormat == "wide",
+		}
+		if len(args) == 1 {
+			options.IngressName = args[0]
+		}
+
+		return ingress_rule.RunList(cmd.Context(), KubernetesConfigFlags, IngressRuleListPrintFlags, options)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	IngressRuleListPrintFlags = genericclioptions.NewPrintFlags("").WithTypeSetter(scheme.Scheme)
+	IngressRuleListPrintFlags.AddFlags(listCmd)
+	listCmd.Flags().Lookup("output").Usage = "Output format. One of: wide|" + strings.Join(IngressRuleListPrintFlags.AllowedFormats(), "|") + "."
+
+	listCmd.Flags().BoolVarP(&IngressRuleListAllNamespaces, "all-namespaces", "A", false, "List the ingress rules across all namespaces")
+}
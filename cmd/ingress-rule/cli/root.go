@@ -22,7 +22,8 @@ var rootCmd = &cobra.Command{
 	Example: "  kubectl ingress-rule set my-ingress --service foo --port 80 --host *.foo.com" +
 		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host example.com --path /foo" +
 		"\n\n  kubectl ingress-rule delete my-ingress --service foo" +
-		"\n  kubectl ingress-rule delete my-ingress --service foo --port 80",
+		"\n  kubectl ingress-rule delete my-ingress --service foo --port 80" +
+		"\n\n  kubectl ingress-rule list my-ingress",
 	Short:   "Add/remove kubernetes ingress rules via command line.",
 	Long:    `Add/remove kubernetes ingress rules via command line.`,
 	Version: version,
@@ -13,9 +13,16 @@ var setCmd = &cobra.Command{
 	Use: "set <ingress-name> [flags]",
 	Example: "  kubectl ingress-rule set my-ingress --service foo --port 80 --host *.foo.com" +
 		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host example.com --path /foo" +
-		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host example.com --tls my-tls-secret",
+		"\n  kubectl ingress-rule set my-ingress --service foo --host example.com" +
+		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host example.com --tls my-tls-secret" +
+		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host foo.local --tls-self-signed --tls-validity 720h --tls-key-type rsa-2048" +
+		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host foo.com --cert-manager-issuer letsencrypt --cluster-issuer --wait-for-certificate" +
+		"\n  kubectl ingress-rule set my-ingress --service foo --port http --host example.com --dry-run=client" +
+		"\n  kubectl ingress-rule set my-ingress --service foo --port 80 --host example.com -f manifest.yaml --in-place" +
+		"\n  kubectl ingress-rule set my-ingress --resource-api-group k8s.example.com --resource-kind StorageBucket --resource-name static-assets --host example.com --path /static" +
+		"\n  kubectl ingress-rule set my-route --target httproute --gateway infra/my-gateway --section-name https --service foo --port 80 --host example.com",
 	Short: "Add kubernetes ingress rules via command line. If the ingress does not exist a new ingress will be created.",
-	Long:  `Adds a backend rule to an ingress. If the ingress does not exist a new ingress will be created.`,
+	Long:  `Adds a backend rule to an ingress. The backend is either a service (--service and optionally --port) or a resource (--resource-kind and --resource-name). If the ingress does not exist a new ingress will be created.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		// validate ingress-name arg
 		if len(args) < 1 {
@@ -45,7 +52,4 @@ func init() {
 	rootCmd.AddCommand(setCmd)
 
 	IngressRuleSetConfigFlags = AddOptionFlags(setCmd.Flags(), COMMAND_SET)
-
-	setCmd.MarkFlagRequired("service")
-	setCmd.MarkFlagRequired("port")
 }
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var IngressRuleSyncFilename string
+var IngressRuleSyncPrune string
+var IngressRuleSyncUpdateFlags *UpdateFlags
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use: "sync -f <rules-file> [flags]",
+	Example: "  kubectl ingress-rule sync -f rules.yaml" +
+		"\n  kubectl ingress-rule sync -f rules.yaml --prune managed --dry-run",
+	Short: "Sync kubernetes ingress rules with a rules file.",
+	Long: `Reconciles the ingresses listed in a rules file with the desired rules in a single update per ingress.
+Rules which are no longer listed are removed according to --prune. Ingresses are created if they do not exist and deleted if there are no rules left.
+
+Example rules file:
+
+  ingresses:
+  - name: my-ingress
+    ingressClassName: nginx
+    rules:
+    - host: foo.com
+      path: /
+      pathType: Prefix
+      service: foo
+      port: 80
+      tls: my-tls-secret`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var prune service.PruneMode
+		switch strings.ToLower(IngressRuleSyncPrune) {
+		case "all":
+			prune = service.PruneAll
+		case "managed":
+			prune = service.PruneManaged
+		case "none":
+			prune = service.PruneNone
+		default:
+			fmt.Println("Invalid prune mode supplied")
+			return errors.New("invalid command line flags supplied")
+		}
+
+		updateOptions := CreateUpdateOptions(IngressRuleSyncUpdateFlags)
+		if updateOptions == nil {
+			return errors.New("invalid command line flags supplied")
+		}
+
+		rulesFile, err := ingress_rule.ReadRulesFile(IngressRuleSyncFilename)
+		if err != nil {
+			return err
+		}
+
+		return ingress_rule.RunSync(cmd.Context(), KubernetesConfigFlags, rulesFile, &ingress_rule.SyncOptions{
+			Prune:         prune,
+			UpdateOptions: *updateOptions,
+		})
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVarP(&IngressRuleSyncFilename, "filename", "f", "", "Rules file containing the desired rules, \"-\" reads from stdin")
+	syncCmd.Flags().StringVar(&IngressRuleSyncPrune, "prune", "managed", "Remove rules which are not listed in the rules file (optional); Accepts: \"all\", \"managed\" (only rules synced before), \"none\"")
+	IngressRuleSyncUpdateFlags = AddUpdateFlags(syncCmd.Flags())
+
+	syncCmd.MarkFlagRequired("filename")
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TlsFlags are the flags of the tls subcommands.
+type TlsFlags struct {
+	Hosts             *[]string
+	Secret            *string
+	From              *string
+	To                *string
+	AllNamespaces     *bool
+	Selector          *string
+	SkipTlsValidation *bool
+	TlsCertFile       *string
+	TlsKeyFile        *string
+	*UpdateFlags
+}
+
+var IngressRuleTlsAddFlags *TlsFlags
+var IngressRuleTlsRemoveFlags *TlsFlags
+var IngressRuleTlsRotateFlags *TlsFlags
+
+// tlsCmd represents the tls command
+var tlsCmd = &cobra.Command{
+	Use:   "tls <command> <ingress-name> [flags]",
+	Short: "Manage the tls configuration of a kubernetes ingress independently of its rules.",
+	Long:  `Adds, removes or rotates the tls secrets of the hosts of an ingress. Tls entries are split or merged as needed.`,
+}
+
+// tlsAddCmd represents the tls add command
+var tlsAddCmd = &cobra.Command{
+	Use: "add <ingress-name> --host <host> --secret <secret> [flags]",
+	Example: "  kubectl ingress-rule tls add my-ingress --host foo.com --secret my-tls-secret" +
+		"\n  kubectl ingress-rule tls add my-ingress --host *.foo.com --secret my-wildcard-secret" +
+		"\n  kubectl ingress-rule tls add my-ingress --host foo.com --secret my-tls-secret --tls-cert-file tls.crt --tls-key-file tls.key",
+	Short: "Enable tls for hosts of an ingress.",
+	Long:  `Configures the tls secret for hosts of an ingress. The ingress needs a rule for every host, a wildcard host has to cover the host of a rule.`,
+	Args:  ingressNameArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTls(cmd, IngressRuleTlsAddFlags, ingress_rule.TlsAdd, args[0])
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// tlsRemoveCmd represents the tls remove command
+var tlsRemoveCmd = &cobra.Command{
+	Use:     "remove <ingress-name> --host <host> [flags]",
+	Example: "  kubectl ingress-rule tls remove my-ingress --host foo.com",
+	Short:   "Disable tls for hosts of an ingress.",
+	Long:    `Removes hosts from the tls configuration of an ingress. With --secret the hosts are only removed from the secret.`,
+	Args:    ingressNameArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTls(cmd, IngressRuleTlsRemoveFlags, ingress_rule.TlsRemove, args[0])
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// tlsRotateCmd represents the tls rotate command
+var tlsRotateCmd = &cobra.Command{
+	Use: "rotate [ingress-name] [flags]",
+	Example: "  kubectl ingress-rule tls rotate my-ingress --host foo.com --secret my-renewed-tls-secret" +
+		"\n  kubectl ingress-rule tls rotate --from my-wildcard-secret --to my-renewed-wildcard-secret -A" +
+		"\n  kubectl ingress-rule tls rotate --from my-wildcard-secret --to my-renewed-wildcard-secret -l app=foo --dry-run",
+	Short: "Move hosts of an ingress to another tls secret.",
+	Long: `Moves hosts which already have a tls configuration to another tls secret, e.g. after a certificate has been renewed to a new secret.
+With --from and --to the secret is replaced in every ingress of the namespace referencing it, use -A for all namespaces and -l to select ingresses by label.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("invalid number of command line arguments; only a ingress name is expected")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ingressName := ""
+		if len(args) == 1 {
+			ingressName = args[0]
+		}
+		return runTls(cmd, IngressRuleTlsRotateFlags, ingress_rule.TlsRotate, ingressName)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(tlsCmd)
+	tlsCmd.AddCommand(tlsAddCmd)
+	tlsCmd.AddCommand(tlsRemoveCmd)
+	tlsCmd.AddCommand(tlsRotateCmd)
+
+	IngressRuleTlsAddFlags = AddTlsFlags(tlsAddCmd.Flags(), ingress_rule.TlsAdd)
+	IngressRuleTlsRemoveFlags = AddTlsFlags(tlsRemoveCmd.Flags(), ingress_rule.TlsRemove)
+	IngressRuleTlsRotateFlags = AddTlsFlags(tlsRotateCmd.Flags(), ingress_rule.TlsRotate)
+}
+
+func runTls(cmd *cobra.Command, flags *TlsFlags, action string, ingressName string) error {
+	options := CreateTlsOptions(flags, action, ingressName)
+	if options == nil {
+		return errors.New("invalid command line flags supplied")
+	}
+
+	return ingress_rule.RunTls(cmd.Context(), KubernetesConfigFlags, options)
+}
+
+func AddTlsFlags(flagSet *pflag.FlagSet, action string) *TlsFlags {
+	tf := &TlsFlags{
+		Hosts:             &[]string{},
+		Secret:            stringptr(""),
+		From:              stringptr(""),
+		To:                stringptr(""),
+		AllNamespaces:     boolptr(false),
+		Selector:          stringptr(""),
+		SkipTlsValidation: boolptr(false),
+		TlsCertFile:       stringptr(""),
+		TlsKeyFile:        stringptr(""),
+	}
+
+	flagSet.StringArrayVar(tf.Hosts, "host", nil, "Host of the tls configuration, may be repeated")
+	if action == ingress_rule.TlsRemove {
+		flagSet.StringVar(tf.Secret, "secret", "", "Only remove the hosts from the tls secret (optional)")
+	} else {
+		flagSet.StringVar(tf.Secret, "secret", "", "Name of the tls secret (must be in the same namespace as the ingress)")
+	}
+	if action == ingress_rule.TlsRotate {
+		flagSet.StringVar(tf.From, "from", "", "Replace this tls secret in every ingress referencing it instead of rotating hosts of a single ingress")
+		flagSet.StringVar(tf.To, "to", "", "Name of the tls secret replacing the secret selected with --from")
+		flagSet.BoolVarP(tf.AllNamespaces, "all-namespaces", "A", false, "Replace the tls secret in ingresses across all namespaces (optional)")
+		flagSet.StringVarP(tf.Selector, "selector", "l", "", "Only replace the tls secret in ingresses matching the label selector (optional)")
+	}
+	if action == ingress_rule.TlsAdd {
+		AddTlsFileFlags(flagSet, tf.TlsCertFile, tf.TlsKeyFile, "--secret")
+	}
+	if action != ingress_rule.TlsRemove {
+		flagSet.BoolVar(tf.SkipTlsValidation, "skip-tls-validation", false, "Use the tls secret without checking that it exists and its certificate covers the hosts (optional)")
+	}
+	tf.UpdateFlags = AddUpdateFlags(flagSet)
+
+	return tf
+}
+
+// CreateTlsOptions validates the flags, at least one host and for add and rotate a secret have to be supplied.
+func CreateTlsOptions(flags *TlsFlags, action string, ingressName string) *ingress_rule.TlsOptions {
+	updateOptions := CreateUpdateOptions(flags.UpdateFlags)
+	if updateOptions == nil {
+		return nil
+	}
+
+	if *flags.From != "" || *flags.To != "" {
+		return createTlsBulkOptions(flags, ingressName, updateOptions)
+	}
+	if *flags.AllNamespaces || *flags.Selector != "" {
+		fmt.Println("Invalid combination of command line arguments: --all-namespaces and --selector require --from and --to")
+		return nil
+	}
+	if ingressName == "" {
+		fmt.Println("No ingress name supplied; use --from and --to to rotate a secret across ingresses")
+		return nil
+	}
+
+	if len(*flags.Hosts) == 0 {
+		fmt.Println("No host supplied")
+		return nil
+	}
+	for _, host := range *flags.Hosts {
+		if !validateHost(host) {
+			return nil
+		}
+	}
+
+	if *flags.Secret == "" && action != ingress_rule.TlsRemove {
+		fmt.Println("No secret supplied")
+		return nil
+	}
+	if *flags.Secret != "" && len(validation.IsDNS1123Subdomain(*flags.Secret)) != 0 {
+		fmt.Println("Invalid secret supplied")
+		return nil
+	}
+	if !validateTlsFiles(*flags.TlsCertFile, *flags.TlsKeyFile, *flags.Secret, "--secret") {
+		return nil
+	}
+
+	return &ingress_rule.TlsOptions{
+		IngressName:       ingressName,
+		Action:            action,
+		Hosts:             *flags.Hosts,
+		Secret:            *flags.Secret,
+		SkipTlsValidation: *flags.SkipTlsValidation,
+		TlsSecretOptions: ingress_rule.TlsSecretOptions{
+			CertFile: *flags.TlsCertFile,
+			KeyFile:  *flags.TlsKeyFile,
+		},
+		UpdateOptions: *updateOptions,
+	}
+}
+
+// createTlsBulkOptions validates the flags of a tls rotation across ingresses, it replaces the rotation of hosts of a single ingress.
+func createTlsBulkOptions(flags *TlsFlags, ingressName string, updateOptions *ingress_rule.UpdateOptions) *ingress_rule.TlsOptions {
+	if ingressName != "" || len(*flags.Hosts) != 0 || *flags.Secret != "" {
+		fmt.Println("Invalid combination of command line arguments: --from and --to can not be combined with an ingress name, --host or --secret")
+		return nil
+	}
+	if *flags.From == "" || *flags.To == "" {
+		fmt.Println("Invalid combination of command line arguments: --from and --to have to be supplied together")
+		return nil
+	}
+	if len(validation.IsDNS1123Subdomain(*flags.From)) != 0 || len(validation.IsDNS1123Subdomain(*flags.To)) != 0 {
+		fmt.Println("Invalid secret supplied")
+		return nil
+	}
+	if *flags.From == *flags.To {
+		fmt.Println("Invalid combination of command line arguments: --from and --to have to be different secrets")
+		return nil
+	}
+	if _, err := labels.Parse(*flags.Selector); err != nil {
+		fmt.Println("Invalid selector supplied")
+		return nil
+	}
+
+	return &ingress_rule.TlsOptions{
+		Action: ingress_rule.TlsRotate,
+		TlsBulkOptions: ingress_rule.TlsBulkOptions{
+			From:          *flags.From,
+			To:            *flags.To,
+			AllNamespaces: *flags.AllNamespaces,
+			LabelSelector: *flags.Selector,
+		},
+		SkipTlsValidation: *flags.SkipTlsValidation,
+		UpdateOptions:     *updateOptions,
+	}
+}
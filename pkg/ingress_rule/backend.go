@@ -0,0 +1,42 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"k8s.io/client-go/kubernetes"
+	"os"
+)
+
+// verifyBackend checks that the backend service exists and defines the port, warnings about missing endpoints are printed to stderr.
+// Resource backends are not verified.
+func verifyBackend(ctx context.Context, clientset *kubernetes.Clientset, namespace string, options *Options) error {
+	if options.ServiceName == "" {
+		return nil
+	}
+
+	warnings, err := service.NewBackendService(clientset, namespace).VerifyServiceBackend(ctx, options.ServiceName, options.ServicePort())
+	if err != nil {
+		return fmt.Errorf("%w; use --no-verify-backend to write the rule anyway", err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// selectServicePort selects the port of the backend service if no port has been supplied.
+func selectServicePort(ctx context.Context, clientset *kubernetes.Clientset, namespace string, options *Options) error {
+	if options.ServiceName == "" || options.PortNumber != 0 || options.PortName != "" {
+		return nil
+	}
+
+	port, err := service.NewBackendService(clientset, namespace).SelectServicePort(ctx, options.ServiceName)
+	if err != nil {
+		return fmt.Errorf("%w; use --port to select the port", err)
+	}
+	options.PortNumber = port.Number
+
+	fmt.Printf("Using port '%s' of service '%s'\n", service.FormatServicePort(port), options.ServiceName)
+	return nil
+}
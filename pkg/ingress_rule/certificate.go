@@ -0,0 +1,127 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"time"
+)
+
+// validateTlsSecret checks that the tls secret exists and its certificate covers the hosts, warnings about the validity are printed to stderr.
+func validateTlsSecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, secret string, hosts []string) error {
+	warnings, err := service.NewSecretService(clientset, namespace).ValidateTlsSecret(ctx, secret, hosts, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w; use --skip-tls-validation to use the secret anyway", err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// findTlsSecret selects the tls secret of the namespace with a valid certificate covering the host and the longest remaining validity.
+func findTlsSecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, host string) (string, error) {
+	secret, cert, err := service.NewSecretService(clientset, namespace).FindTlsSecret(ctx, host, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Using tls secret '%s' for host '%s' (valid until %s)\n", secret, host, cert.NotAfter.Format(time.RFC3339))
+	return secret, nil
+}
+
+// ensureSelfSignedSecret creates or renews the secret with a self-signed certificate for the host and returns its name.
+// An existing secret is kept as long as its certificate covers the host and more than a third of the validity remains.
+func ensureSelfSignedSecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, host string, options *Options) (string, error) {
+	name := service.SelfSignedSecretName(host)
+	secretService := service.NewSecretService(clientset, namespace)
+	options.configure(secretService)
+
+	now := time.Now()
+	existing, err := secretService.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && service.SelfSignedCertificateReusable(existing, []string{host}, options.Validity, now) {
+		fmt.Printf("Using existing self-signed tls secret '%s' for host '%s'\n", name, host)
+		return name, nil
+	}
+
+	certPEM, keyPEM, err := service.GenerateSelfSignedCertificate([]string{host}, options.Validity, options.KeyType, now)
+	if err != nil {
+		return "", err
+	}
+	created, err := secretService.CreateOrUpdateTlsSecret(ctx, name, certPEM, keyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	action := "Updated"
+	if created {
+		action = "Created"
+	}
+	fmt.Printf("%s self-signed tls secret '%s' for host '%s' (valid until %s)%s\n",
+		action, name, host, now.Add(options.Validity).Format(time.RFC3339), options.dryRunSuffix())
+	return name, nil
+}
+
+// createTlsSecretFromFiles creates or updates the tls secret from the certificate and key files after checking that they match and the certificate covers the hosts.
+// Warnings about the validity are printed to stderr.
+func createTlsSecretFromFiles(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, hosts []string, files *TlsSecretOptions, options *UpdateOptions) error {
+	certPEM, err := os.ReadFile(files.CertFile)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	keyPEM, err := os.ReadFile(files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	warnings, err := service.ValidateTlsKeyPair(name, certPEM, keyPEM, hosts, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	secretService := service.NewSecretService(clientset, namespace)
+	options.configure(secretService)
+	created, err := secretService.CreateOrUpdateTlsSecret(ctx, name, certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	action := "Updated"
+	if created {
+		action = "Created"
+	}
+	fmt.Printf("%s tls secret '%s' in namespace '%s' from '%s' and '%s'%s\n", action, name, namespace, files.CertFile, files.KeyFile, options.dryRunSuffix())
+	return nil
+}
+
+// waitForCertificate waits until cert-manager reports the Certificate of the secret as ready.
+func waitForCertificate(ctx context.Context, configFlags *genericclioptions.ConfigFlags, namespace string, secret string, timeout time.Duration) error {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	fmt.Printf("Waiting up to %s for certificate '%s' to be ready\n", timeout, secret)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err = service.NewCertificateService(client, namespace).WaitForCertificate(ctx, secret, 2*time.Second); err != nil {
+		return err
+	}
+
+	fmt.Printf("Certificate '%s' is ready\n", secret)
+	return nil
+}
@@ -0,0 +1,165 @@
+package ingress_rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunCerts prints the certificates of the tls entries and the hosts without tls of all ingresses in the selected format.
+func RunCerts(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *CertsOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace := metav1.NamespaceAll
+	if !options.AllNamespaces {
+		namespace, _, err = configFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	ingressList, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var rows []service.CertificateReportRow
+	for i := range ingressList.Items {
+		ingress := &ingressList.Items[i]
+		secrets, secretErrors := referencedTlsSecrets(ctx, clientset, ingress)
+		rows = append(rows, service.CertificateReport(ingress, secrets, secretErrors, now)...)
+	}
+
+	switch options.Output {
+	case CertsOutputJson:
+		return printCertsJson(os.Stdout, rows)
+	case CertsOutputPrometheus:
+		return printCertsPrometheus(os.Stdout, rows)
+	default:
+		return printCertsTable(os.Stdout, rows, options)
+	}
+}
+
+// referencedTlsSecrets reads the secrets referenced by the tls entries of the ingress, secrets which do not exist are omitted.
+// Secrets which can not be read, e.g. without permission in the namespace, are returned as errors so the report covers all other ingresses.
+func referencedTlsSecrets(ctx context.Context, clientset kubernetes.Interface, ingress *networking.Ingress) (map[string]*core.Secret, map[string]error) {
+	secrets := map[string]*core.Secret{}
+	secretErrors := map[string]error{}
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if _, ok := secrets[tlsEntry.SecretName]; ok || tlsEntry.SecretName == "" {
+			continue
+		}
+		if _, ok := secretErrors[tlsEntry.SecretName]; ok {
+			continue
+		}
+		secret, err := clientset.CoreV1().Secrets(ingress.Namespace).Get(ctx, tlsEntry.SecretName, metav1.GetOptions{})
+		if apierror.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			secretErrors[tlsEntry.SecretName] = err
+			continue
+		}
+		secrets[tlsEntry.SecretName] = secret
+	}
+	return secrets, secretErrors
+}
+
+func printCertsTable(out io.Writer, rows []service.CertificateReportRow, options *CertsOptions) error {
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "No ingress hosts found.")
+		return nil
+	}
+
+	headers := []string{"INGRESS", "HOST", "SECRET", "ISSUER", "NOT-AFTER", "DAYS", "COVERED", "STATUS"}
+	if options.AllNamespaces {
+		headers = append([]string{"NAMESPACE"}, headers...)
+	}
+
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		notAfter, days, covered := "<none>", "<none>", "<none>"
+		if row.NotAfter != nil {
+			notAfter = row.NotAfter.Format(time.RFC3339)
+			days = strconv.Itoa(*row.DaysRemaining)
+			covered = strconv.FormatBool(row.Covered)
+		}
+		columns := []string{row.Ingress, valueOrDefault(row.Host, "<default>"), valueOrDefault(row.Secret, "<none>"), valueOrDefault(row.Issuer, "<none>"), notAfter, days, covered, string(row.Status)}
+		if options.AllNamespaces {
+			columns = append([]string{row.Namespace}, columns...)
+		}
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func printCertsJson(out io.Writer, rows []service.CertificateReportRow) error {
+	if rows == nil {
+		rows = []service.CertificateReportRow{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// printCertsPrometheus writes the report in the Prometheus text format, e.g. for the textfile collector of the node exporter.
+func printCertsPrometheus(out io.Writer, rows []service.CertificateReportRow) error {
+	fmt.Fprintln(out, "# HELP ingress_rule_certificate_expiry_timestamp_seconds Expiry of the certificate chain of the tls host as unix timestamp.")
+	fmt.Fprintln(out, "# TYPE ingress_rule_certificate_expiry_timestamp_seconds gauge")
+	for _, row := range rows {
+		if row.NotAfter != nil {
+			fmt.Fprintf(out, "ingress_rule_certificate_expiry_timestamp_seconds{%s,issuer=\"%s\"} %d\n", prometheusLabels(row), prometheusEscape(row.Issuer), row.NotAfter.Unix())
+		}
+	}
+
+	fmt.Fprintln(out, "# HELP ingress_rule_certificate_host_covered Whether the certificate covers the tls host.")
+	fmt.Fprintln(out, "# TYPE ingress_rule_certificate_host_covered gauge")
+	for _, row := range rows {
+		if row.NotAfter != nil {
+			fmt.Fprintf(out, "ingress_rule_certificate_host_covered{%s} %d\n", prometheusLabels(row), boolToInt(row.Covered))
+		}
+	}
+
+	fmt.Fprintln(out, "# HELP ingress_rule_certificate_status Status of the tls configuration of the host, the current status has the value 1.")
+	fmt.Fprintln(out, "# TYPE ingress_rule_certificate_status gauge")
+	for _, row := range rows {
+		fmt.Fprintf(out, "ingress_rule_certificate_status{%s,status=\"%s\"} 1\n", prometheusLabels(row), row.Status)
+	}
+	return nil
+}
+
+// prometheusLabels returns the labels identifying the host of the row.
+func prometheusLabels(row service.CertificateReportRow) string {
+	return fmt.Sprintf("namespace=\"%s\",ingress=\"%s\",host=\"%s\",secret=\"%s\"",
+		prometheusEscape(row.Namespace), prometheusEscape(row.Ingress), prometheusEscape(row.Host), prometheusEscape(row.Secret))
+}
+
+// prometheusEscape escapes a label value of the Prometheus text format.
+func prometheusEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
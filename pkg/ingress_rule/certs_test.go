@@ -0,0 +1,75 @@
+package ingress_rule
+
+import (
+	"bytes"
+	"context"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestReferencedTlsSecrets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&core.Secret{ObjectMeta: metav1.ObjectMeta{Name: "readable", Namespace: "default"}, Type: core.SecretTypeTLS})
+	clientset.PrependReactor("get", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.GetAction).GetName() == "forbidden" {
+			return true, nil, apierror.NewForbidden(core.Resource("secrets"), "forbidden", nil)
+		}
+		return false, nil, nil
+	})
+
+	ingress := &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-ingress", Namespace: "default"},
+		Spec: networking.IngressSpec{TLS: []networking.IngressTLS{
+			{Hosts: []string{"foo.com"}, SecretName: "readable"},
+			{Hosts: []string{"bar.com"}, SecretName: "forbidden"},
+			{Hosts: []string{"baz.com"}, SecretName: "missing"},
+		}},
+	}
+
+	secrets, secretErrors := referencedTlsSecrets(context.TODO(), clientset, ingress)
+	assert.Len(t, secrets, 1)
+	assert.Contains(t, secrets, "readable")
+	assert.Len(t, secretErrors, 1)
+	assert.True(t, apierror.IsForbidden(secretErrors["forbidden"]))
+}
+
+func TestPrintCertsPrometheus(t *testing.T) {
+	notAfter := time.Unix(1700000000, 0)
+	days := 30
+	rows := []service.CertificateReportRow{
+		{Namespace: "default", Ingress: "my-ingress", Host: "foo.com", Secret: "foo-tls", Issuer: `Acme "CA"\Root`, NotAfter: &notAfter, DaysRemaining: &days, Covered: true, Status: service.CertificateOK},
+		{Namespace: "default", Ingress: "my-ingress", Host: "bar.com", Status: service.CertificateNoTls},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printCertsPrometheus(out, rows))
+	assert.Equal(t, `# HELP ingress_rule_certificate_expiry_timestamp_seconds Expiry of the certificate chain of the tls host as unix timestamp.
+# TYPE ingress_rule_certificate_expiry_timestamp_seconds gauge
+ingress_rule_certificate_expiry_timestamp_seconds{namespace="default",ingress="my-ingress",host="foo.com",secret="foo-tls",issuer="Acme \"CA\"\\Root"} 1700000000
+# HELP ingress_rule_certificate_host_covered Whether the certificate covers the tls host.
+# TYPE ingress_rule_certificate_host_covered gauge
+ingress_rule_certificate_host_covered{namespace="default",ingress="my-ingress",host="foo.com",secret="foo-tls"} 1
+# HELP ingress_rule_certificate_status Status of the tls configuration of the host, the current status has the value 1.
+# TYPE ingress_rule_certificate_status gauge
+ingress_rule_certificate_status{namespace="default",ingress="my-ingress",host="foo.com",secret="foo-tls",status="OK"} 1
+ingress_rule_certificate_status{namespace="default",ingress="my-ingress",host="bar.com",secret="",status="NoTls"} 1
+`, out.String())
+}
+
+func TestPrintCertsJson(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, printCertsJson(out, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestPrometheusEscape(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, prometheusEscape("a\\b\"c\nd"))
+}
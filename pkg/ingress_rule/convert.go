@@ -0,0 +1,123 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"os"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// RunConvert converts the ingress to HTTPRoutes and writes them to stdout or applies them.
+// The gateway listeners can not be applied since the gateway is usually managed separately, they are always written to stdout.
+// Constructs which could not be converted are reported on stderr.
+func RunConvert(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *ConvertOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := existingNamespace(ctx, configFlags, clientset)
+	if err != nil {
+		return err
+	}
+
+	ingress, err := clientset.NetworkingV1().Ingresses(namespace).Get(ctx, options.IngressName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	conversion := service.ConvertIngress(ingress, options.ParentRefs())
+	for _, unsupported := range conversion.Unsupported {
+		fmt.Fprintf(os.Stderr, "Not converted: %s\n", unsupported)
+	}
+	if options.Gateway == "" {
+		fmt.Fprintln(os.Stderr, "HTTPRoutes are not attached to a gateway; use --gateway")
+	}
+
+	if options.Apply {
+		if err = applyRoutes(ctx, configFlags, namespace, conversion.Routes, options); err != nil {
+			return err
+		}
+	} else {
+		for _, route := range conversion.Routes {
+			data, err := marshalHTTPRoute(route)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("---\n%s", data)
+		}
+	}
+
+	return printListeners(os.Stdout, conversion.Listeners, options)
+}
+
+func applyRoutes(ctx context.Context, configFlags *genericclioptions.ConfigFlags, namespace string, routes []*gateway.HTTPRoute, options *ConvertOptions) error {
+	gatewayClientset, err := newGatewayClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		routeService := service.NewHTTPRouteService(gatewayClientset, namespace, route.Name, nil)
+		options.configure(routeService)
+
+		created, err := routeService.Apply(ctx, route)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Printf("Created HTTPRoute '%s'%s\n", route.Name, options.dryRunSuffix())
+		} else {
+			fmt.Printf("Updated HTTPRoute '%s'%s\n", route.Name, options.dryRunSuffix())
+		}
+	}
+
+	return nil
+}
+
+// printListeners writes the listeners as commented yaml snippet, they have to be merged into the gateway.
+func printListeners(out io.Writer, listeners []gateway.Listener, options *ConvertOptions) error {
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{"listeners": listeners})
+	if err != nil {
+		return err
+	}
+
+	target := "the gateway"
+	if options.Gateway != "" {
+		target = fmt.Sprintf("gateway '%s'", options.Gateway)
+		if options.GatewayNamespace != "" {
+			target = fmt.Sprintf("gateway '%s/%s'", options.GatewayNamespace, options.Gateway)
+		}
+	}
+
+	fmt.Fprintf(out, "# Listeners terminating tls for the HTTPRoutes, add them to %s:\n", target)
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		fmt.Fprintf(out, "# %s\n", line)
+	}
+	return nil
+}
+
+// marshalHTTPRoute encodes the HTTPRoute as yaml without the empty status and creation timestamp.
+func marshalHTTPRoute(route *gateway.HTTPRoute) ([]byte, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(route)
+	if err != nil {
+		return nil, err
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok && metadata["creationTimestamp"] == nil {
+		delete(metadata, "creationTimestamp")
+	}
+	delete(obj, "status")
+
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,63 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// RunDefaultBackend sets or removes the default backend of the ingress.
+func RunDefaultBackend(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *DefaultBackendOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := existingNamespace(ctx, configFlags, clientset)
+	if err != nil {
+		return err
+	}
+
+	ingressService := service.NewIngressService(clientset, namespace, options.IngressName, options.IngressClassName)
+	options.configure(ingressService)
+	if options.Unset {
+		return unsetDefaultBackend(ctx, ingressService, options)
+	}
+	return setDefaultBackend(ctx, ingressService, options)
+}
+
+func setDefaultBackend(ctx context.Context, ingressService *service.IngressService, options *DefaultBackendOptions) error {
+	backend := options.Backend()
+
+	replaced, created, err := ingressService.SetDefaultBackend(ctx, backend)
+	if err == service.ErrDefaultBackendAlreadyExists {
+		fmt.Println("Doing nothing: Default backend already exists")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if replaced != nil {
+		fmt.Printf("Replaced default backend %s of ingress '%s' with %s%s\n", service.FormatBackend(*replaced), options.IngressName, service.FormatBackend(backend), options.dryRunSuffix())
+	} else {
+		fmt.Printf("Set default backend %s for ingress '%s'%s\n", service.FormatBackend(backend), options.IngressName, options.dryRunSuffix())
+	}
+	if created {
+		fmt.Printf("Created ingress '%s'%s\n", options.IngressName, options.dryRunSuffix())
+	}
+	return nil
+}
+
+func unsetDefaultBackend(ctx context.Context, ingressService *service.IngressService, options *DefaultBackendOptions) error {
+	deleted, err := ingressService.UnsetDefaultBackend(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed default backend from ingress '%s'%s\n", options.IngressName, options.dryRunSuffix())
+	if deleted {
+		fmt.Printf("Deleted ingress '%s'%s\n", options.IngressName, options.dryRunSuffix())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	core "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"strings"
+)
+
+// RunExpose creates a ClusterIP service for the workload unless the service already exists and adds a rule routing to it.
+// A service created by the command is deleted again if the rule can not be added.
+func RunExpose(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *ExposeOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := existingNamespace(ctx, configFlags, clientset)
+	if err != nil {
+		return err
+	}
+
+	if options.TlsSecret != "" && !options.SkipTlsValidation {
+		if err = validateTlsSecret(ctx, clientset, namespace, options.TlsSecret, []string{options.Host}); err != nil {
+			return err
+		}
+	}
+
+	exposeService := service.NewExposeService(clientset, namespace)
+	options.configure(exposeService)
+
+	backend, err := exposeService.GetService(ctx, options.ServiceName)
+	if err != nil {
+		return err
+	}
+	created := backend == nil
+	if created {
+		if backend, err = createWorkloadService(ctx, exposeService, options); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Using existing service '%s'\n", options.ServiceName)
+	}
+
+	if options.PortNumber == 0 {
+		port, err := service.SelectServicePort(backend)
+		if err != nil {
+			return rollbackService(ctx, exposeService, options, created, fmt.Errorf("%w; use --port to select the port", err))
+		}
+		options.PortNumber = port.Number
+		fmt.Printf("Using port '%s' of service '%s'\n", service.FormatServicePort(port), options.ServiceName)
+	}
+	if !created {
+		if err = verifyBackend(ctx, clientset, namespace, &options.Options); err != nil {
+			return err
+		}
+	}
+
+	ingressService := service.NewIngressService(clientset, namespace, options.IngressName, options.IngressClassName)
+	options.configure(ingressService)
+	if err = addRule(ctx, ingressService, &options.Options); err != nil {
+		return rollbackService(ctx, exposeService, options, created, err)
+	}
+	return nil
+}
+
+// createWorkloadService creates the ClusterIP service selecting the pods of the workload.
+func createWorkloadService(ctx context.Context, exposeService *service.ExposeService, options *ExposeOptions) (*core.Service, error) {
+	workload, err := exposeService.GetWorkload(ctx, options.WorkloadKind, options.WorkloadName)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := service.ServiceForWorkload(options.ServiceName, workload, options.PortNumber, options.TargetPort)
+	if err != nil {
+		return nil, fmt.Errorf("%w; use --port and --target-port to select the ports", err)
+	}
+	if err = exposeService.CreateService(ctx, backend); err != nil {
+		return nil, err
+	}
+
+	var ports []string
+	for _, port := range backend.Spec.Ports {
+		ports = append(ports, fmt.Sprintf("%s -> %s", service.FormatServicePortSpec(port), port.TargetPort.String()))
+	}
+	fmt.Printf("Created service '%s' for %s '%s' (ports: %s)%s\n", backend.Name, workload.Kind, workload.Name, strings.Join(ports, ", "), options.dryRunSuffix())
+	return backend, nil
+}
+
+// rollbackService deletes the service created by the command, the error causing the rollback is returned.
+func rollbackService(ctx context.Context, exposeService *service.ExposeService, options *ExposeOptions, created bool, cause error) error {
+	if !created || options.dryRunStrategy() != service.DryRunNone {
+		return cause
+	}
+
+	if err := exposeService.DeleteService(ctx, options.ServiceName); err != nil {
+		return fmt.Errorf("%w; rollback failed, delete service '%s' manually: %s", cause, options.ServiceName, err)
+	}
+	fmt.Printf("Rolled back: deleted service '%s'\n", options.ServiceName)
+	return cause
+}
@@ -0,0 +1,263 @@
+package ingress_rule
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/manifest"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+const FunctionConfigKind = "IngressRuleConfig"
+
+const (
+	FunctionActionSet    = "set"
+	FunctionActionDelete = "delete"
+)
+
+// FunctionConfig is the functionConfig of the KRM function, it lists the rule operations applied to the ingresses.
+type FunctionConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              FunctionConfigSpec `json:"spec"`
+}
+
+type FunctionConfigSpec struct {
+	Operations []FunctionOperation `json:"operations"`
+}
+
+// FunctionOperation adds a rule to or removes rules from an ingress, the fields correspond to the command line flags of set and delete.
+type FunctionOperation struct {
+	Action           string              `json:"action"`
+	Ingress          string              `json:"ingress"`
+	Namespace        string              `json:"namespace,omitempty"`
+	IngressClassName string              `json:"ingressClassName,omitempty"`
+	Host             *string             `json:"host,omitempty"`
+	Path             string              `json:"path,omitempty"`
+	PathType         string              `json:"pathType,omitempty"`
+	Service          string              `json:"service,omitempty"`
+	Port             *intstr.IntOrString `json:"port,omitempty"`
+	Tls              string              `json:"tls,omitempty"`
+	Overwrite        bool                `json:"overwrite,omitempty"`
+	AllPathsForHost  bool                `json:"allPathsForHost,omitempty"`
+}
+
+// functionItem is an item of the ResourceList, ingress is only decoded for networking.k8s.io/v1 ingresses.
+type functionItem struct {
+	node     *kyaml.RNode
+	ingress  *networking.Ingress
+	modified bool
+	deleted  bool
+}
+
+// RunFunction reads a ResourceList, applies the operations of its functionConfig to the ingresses and writes the ResourceList.
+func RunFunction(in io.Reader, out io.Writer) error {
+	return framework.Execute(framework.ResourceListProcessorFunc(processResourceList), &kio.ByteReadWriter{
+		Reader:                in,
+		Writer:                out,
+		KeepReaderAnnotations: true,
+	})
+}
+
+// processResourceList applies the operations to the items of the ResourceList.
+// The items are only replaced if all operations succeed.
+func processResourceList(rl *framework.ResourceList) error {
+	config, err := loadFunctionConfig(rl.FunctionConfig)
+	if err != nil {
+		return err
+	}
+
+	var items []*functionItem
+	for _, node := range rl.Items {
+		item := &functionItem{node: node}
+		if node.GetApiVersion() == networking.SchemeGroupVersion.String() && node.GetKind() == "Ingress" {
+			item.ingress = &networking.Ingress{}
+			if err = yaml.Unmarshal([]byte(node.MustString()), item.ingress); err != nil {
+				return fmt.Errorf("failed to decode ingress '%s': %w", node.GetName(), err)
+			}
+		}
+		items = append(items, item)
+	}
+
+	for i, operation := range config.Spec.Operations {
+		if items, err = applyFunctionOperation(items, operation); err != nil {
+			return fmt.Errorf("operation %d (%s ingress '%s'): %w", i, operation.Action, operation.Ingress, err)
+		}
+	}
+
+	var result []*kyaml.RNode
+	for _, item := range items {
+		if item.deleted {
+			continue
+		}
+		if item.modified {
+			data, err := manifest.MarshalIngress(item.ingress)
+			if err != nil {
+				return err
+			}
+			if item.node, err = kyaml.Parse(string(data)); err != nil {
+				return err
+			}
+		}
+		result = append(result, item.node)
+	}
+
+	rl.Items = result
+	return nil
+}
+
+// loadFunctionConfig decodes and validates the functionConfig.
+func loadFunctionConfig(node *kyaml.RNode) (*FunctionConfig, error) {
+	if node == nil {
+		return nil, errors.New("no functionConfig supplied")
+	}
+
+	config := &FunctionConfig{}
+	if err := yaml.UnmarshalStrict([]byte(node.MustString()), config); err != nil {
+		return nil, fmt.Errorf("failed to parse functionConfig: %w", err)
+	}
+	if config.Kind != FunctionConfigKind {
+		return nil, fmt.Errorf("unsupported functionConfig kind '%s', expected '%s'", config.Kind, FunctionConfigKind)
+	}
+
+	for i, operation := range config.Spec.Operations {
+		if operation.Ingress == "" {
+			return nil, fmt.Errorf("invalid functionConfig: operation %d without ingress", i)
+		}
+		if operation.Action != FunctionActionSet && operation.Action != FunctionActionDelete {
+			return nil, fmt.Errorf("invalid functionConfig: operation %d has unknown action '%s'", i, operation.Action)
+		}
+	}
+
+	return config, nil
+}
+
+// applyFunctionOperation applies the operation to all matching ingresses.
+// Operations are idempotent, existing rules are not added again and missing rules are not reported as error.
+func applyFunctionOperation(items []*functionItem, operation FunctionOperation) ([]*functionItem, error) {
+	var matching []*functionItem
+	for _, item := range items {
+		if item.ingress == nil || item.deleted || item.ingress.Name != operation.Ingress {
+			continue
+		}
+		if operation.Namespace != "" && item.ingress.Namespace != "" && item.ingress.Namespace != operation.Namespace {
+			continue
+		}
+		matching = append(matching, item)
+	}
+
+	if operation.Action == FunctionActionSet {
+		d, err := operation.desiredRule()
+		if err != nil {
+			return nil, err
+		}
+		rule := service.CreateIngressRule(d.Host, d.Path, d.PathType, d.ServiceName, d.ServicePort)
+
+		if len(matching) == 0 {
+			ingress := service.NewIngress(operation.Ingress, operation.IngressClassName)
+			ingress.Namespace = operation.Namespace
+			if err = service.ApplyAddRule(ingress, rule, d.TlsSecret, operation.Overwrite); err != nil {
+				return nil, err
+			}
+			return append(items, &functionItem{ingress: ingress, modified: true}), nil
+		}
+
+		for _, item := range matching {
+			err = service.ApplyAddRule(item.ingress, rule, d.TlsSecret, operation.Overwrite)
+			if err == service.ErrIngressRuleAlreadyExists {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			item.modified = true
+		}
+		return items, nil
+	}
+
+	selector, err := operation.ruleSelector()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range matching {
+		empty, _, err := service.ApplyDeleteRule(item.ingress, selector)
+		if err == service.ErrIngressRuleNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		// delete ingress when the last rule is removed
+		item.deleted = empty
+		item.modified = true
+	}
+	return items, nil
+}
+
+// desiredRule validates the rule of a set operation.
+func (o FunctionOperation) desiredRule() (service.DesiredRule, error) {
+	rule := RulesFileRule{
+		Path:     o.Path,
+		PathType: o.PathType,
+		Service:  o.Service,
+		Tls:      o.Tls,
+	}
+	if o.Host != nil {
+		rule.Host = *o.Host
+	}
+	if o.Port == nil {
+		return service.DesiredRule{}, errors.New("no port supplied")
+	}
+	rule.Port = *o.Port
+
+	return rule.desiredRule()
+}
+
+// ruleSelector validates the selectors of a delete operation like the command line flags of delete.
+func (o FunctionOperation) ruleSelector() (service.RuleSelector, error) {
+	selector := service.RuleSelector{
+		Host:        o.Host,
+		Path:        o.Path,
+		ServiceName: o.Service,
+	}
+
+	if o.Path != "" && !strings.HasPrefix(o.Path, "/") {
+		return service.RuleSelector{}, fmt.Errorf("invalid path '%s'", o.Path)
+	}
+	if o.PathType != "" {
+		pathType, err := ParsePathType(o.PathType)
+		if err != nil {
+			return service.RuleSelector{}, err
+		}
+		selector.PathType = &pathType
+	}
+	if o.Port != nil {
+		port, err := parseServicePort(*o.Port)
+		if err != nil {
+			return service.RuleSelector{}, err
+		}
+		selector.ServicePort = port
+	}
+
+	otherSelectorSupplied := selector.Path != "" || selector.PathType != nil || selector.ServiceName != "" || o.Port != nil
+	if o.AllPathsForHost && o.Host == nil {
+		return service.RuleSelector{}, errors.New("allPathsForHost requires a host")
+	}
+	if o.AllPathsForHost && otherSelectorSupplied {
+		return service.RuleSelector{}, errors.New("allPathsForHost can not be combined with other selectors")
+	}
+	if o.Host != nil && !otherSelectorSupplied && !o.AllPathsForHost {
+		return service.RuleSelector{}, errors.New("selecting rules only by host removes all paths of the host; set allPathsForHost to confirm")
+	}
+	if selector.IsEmpty() {
+		return service.RuleSelector{}, errors.New("no selector supplied; select rules by host, path, pathType, service or port")
+	}
+
+	return selector, nil
+}
@@ -0,0 +1,167 @@
+package ingress_rule
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"testing"
+)
+
+const functionInput = `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+  spec:
+    ports:
+    - port: 80
+- apiVersion: networking.k8s.io/v1
+  kind: Ingress
+  metadata:
+    name: my-ingress
+  spec:
+    rules:
+    - host: bar.com
+      http:
+        paths:
+        - path: /
+          pathType: Prefix
+          backend:
+            service:
+              name: bar
+              port:
+                number: 80
+functionConfig:
+  apiVersion: ingress-rule.pragaonj.github.io/v1alpha1
+  kind: IngressRuleConfig
+  metadata:
+    name: dev-rules
+  spec:
+    operations:
+`
+
+type functionOutput struct {
+	Items []map[string]interface{} `json:"items"`
+}
+
+func TestRunFunction(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		err        string
+		ingresses  map[string][]string // ingress name -> hosts of the rules
+	}{
+		{
+			name: "add rule to existing ingress",
+			operations: `
+    - action: set
+      ingress: my-ingress
+      host: foo.com
+      service: foo
+      port: http
+      tls: foo-tls
+`,
+			ingresses: map[string][]string{"my-ingress": {"bar.com", "foo.com"}},
+		},
+		{
+			name: "add rule to new ingress",
+			operations: `
+    - action: set
+      ingress: other
+      service: foo
+      port: 80
+`,
+			ingresses: map[string][]string{"my-ingress": {"bar.com"}, "other": {""}},
+		},
+		{
+			name: "existing rule is skipped",
+			operations: `
+    - action: set
+      ingress: my-ingress
+      host: bar.com
+      service: bar
+      port: 80
+`,
+			ingresses: map[string][]string{"my-ingress": {"bar.com"}},
+		},
+		{
+			name: "delete last rule removes ingress",
+			operations: `
+    - action: delete
+      ingress: my-ingress
+      service: bar
+`,
+			ingresses: map[string][]string{},
+		},
+		{
+			name: "missing rule is skipped",
+			operations: `
+    - action: delete
+      ingress: my-ingress
+      service: foo
+`,
+			ingresses: map[string][]string{"my-ingress": {"bar.com"}},
+		},
+		{
+			name: "backend conflict fails",
+			operations: `
+    - action: set
+      ingress: my-ingress
+      host: bar.com
+      service: foo
+      port: 80
+`,
+			err: "backend",
+		},
+		{
+			name: "host only selector requires allPathsForHost",
+			operations: `
+    - action: delete
+      ingress: my-ingress
+      host: bar.com
+`,
+			err: "allPathsForHost",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := RunFunction(strings.NewReader(functionInput+strings.TrimPrefix(test.operations, "\n")), &out)
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+
+			output := functionOutput{}
+			assert.NoError(t, yaml.Unmarshal(out.Bytes(), &output))
+
+			ingresses := map[string][]string{}
+			services := 0
+			for _, item := range output.Items {
+				if item["kind"] == "Service" {
+					services++
+					continue
+				}
+				data, err := yaml.Marshal(item)
+				assert.NoError(t, err)
+				ingress := networking.Ingress{}
+				assert.NoError(t, yaml.Unmarshal(data, &ingress))
+
+				var hosts []string
+				for _, rule := range ingress.Spec.Rules {
+					hosts = append(hosts, rule.Host)
+				}
+				ingresses[ingress.Name] = hosts
+			}
+			assert.Equal(t, 1, services, "other resources are preserved")
+			assert.Equal(t, test.ingresses, ingresses)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"os"
+	gatewayversioned "sigs.k8s.io/gateway-api/pkg/client/clientset/gateway/versioned"
+)
+
+// runHTTPRoute applies the command to a Gateway API HTTPRoute instead of an ingress.
+func runHTTPRoute(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *Options) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := existingNamespace(ctx, configFlags, clientset)
+	if err != nil {
+		return err
+	}
+
+	if options.Set {
+		if err = selectServicePort(ctx, clientset, namespace, options); err != nil {
+			return err
+		}
+	}
+	if options.Set && !options.NoVerifyBackend {
+		if err = verifyBackend(ctx, clientset, namespace, options); err != nil {
+			return err
+		}
+	}
+
+	gatewayClientset, err := newGatewayClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	routeService := service.NewHTTPRouteService(gatewayClientset, namespace, options.IngressName, options.ParentRefs())
+	options.configure(routeService)
+	if options.Set {
+		return addRouteRule(ctx, routeService, options)
+	} else if options.Delete {
+		return deleteRouteRule(ctx, routeService, options)
+	}
+
+	return nil
+}
+
+func newGatewayClientset(configFlags *genericclioptions.ConfigFlags) (*gatewayversioned.Clientset, error) {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	clientset, err := gatewayversioned.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway clientset: %w", err)
+	}
+
+	return clientset, nil
+}
+
+func addRouteRule(ctx context.Context, routeService *service.HTTPRouteService, options *Options) error {
+	rule, err := service.CreateHTTPRouteRule(options.Path, options.PathType, options.ServiceName, options.ServicePort())
+	if err != nil {
+		return err
+	}
+
+	created, err := routeService.AddRule(ctx, options.Host, rule, options.Overwrite)
+	if err == service.ErrIngressRuleAlreadyExists {
+		fmt.Println("Doing nothing: HTTPRoute rule already exists")
+		return nil
+	} else if err == service.ErrNoParentRef {
+		return fmt.Errorf("%w; use --gateway", err)
+	} else if err != nil {
+		return withConflictHint(err)
+	}
+
+	printAddRuleResult(os.Stdout, options, created)
+	return nil
+}
+
+func deleteRouteRule(ctx context.Context, routeService *service.HTTPRouteService, options *Options) error {
+	deleted, err := routeService.DeleteRule(ctx, options.RuleSelector())
+	if err != nil {
+		return err
+	}
+
+	printDeleteRuleResult(os.Stdout, options, deleted, nil)
+	return nil
+}
@@ -0,0 +1,140 @@
+package ingress_rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// RunList prints the rules of one or all ingresses either as table or with the printer selected by the print flags.
+func RunList(ctx context.Context, configFlags *genericclioptions.ConfigFlags, printFlags *genericclioptions.PrintFlags, options *ListOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace := metav1.NamespaceAll
+	if !options.AllNamespaces {
+		namespace, _, err = configFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	var ingresses []networking.Ingress
+	if options.IngressName != "" && !options.AllNamespaces {
+		ingress, err := clientset.NetworkingV1().Ingresses(namespace).Get(ctx, options.IngressName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ingresses = append(ingresses, *ingress)
+	} else {
+		ingressList, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, ingress := range ingressList.Items {
+			if options.IngressName == "" || ingress.Name == options.IngressName {
+				ingresses = append(ingresses, ingress)
+			}
+		}
+	}
+
+	var rows []service.IngressRuleRow
+	for i := range ingresses {
+		rows = append(rows, service.FlattenIngressRules(&ingresses[i])...)
+	}
+
+	outputFormat := ""
+	if printFlags.OutputFormat != nil {
+		outputFormat = *printFlags.OutputFormat
+	}
+	switch outputFormat {
+	case "", "wide":
+		return printRuleTable(os.Stdout, rows, options)
+	case "json":
+		return printRulesJson(os.Stdout, rows)
+	case "yaml":
+		return printRulesYaml(os.Stdout, rows)
+	}
+
+	// templates and names are printed from the ingresses
+	printer, err := printFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	if options.IngressName != "" && len(ingresses) == 1 {
+		return printer.PrintObj(&ingresses[0], os.Stdout)
+	}
+	return printer.PrintObj(&networking.IngressList{Items: ingresses}, os.Stdout)
+}
+
+func printRuleTable(out io.Writer, rows []service.IngressRuleRow, options *ListOptions) error {
+	if len(rows) == 0 {
+		// written to stderr to keep the output clean for scripts
+		fmt.Fprintln(os.Stderr, "No ingress rules found.")
+		return nil
+	}
+
+	headers := []string{"INGRESS", "HOST", "PATH", "PATH-TYPE", "SERVICE", "PORT", "TLS-SECRET"}
+	if options.AllNamespaces {
+		headers = append([]string{"NAMESPACE"}, headers...)
+	}
+	if options.Wide {
+		headers = append(headers, "INGRESS-CLASS")
+	}
+
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		columns := []string{row.Ingress, valueOrDefault(row.Host, "*"), row.Path, row.PathType, valueOrDefault(row.Service, row.Resource), row.Port, valueOrDefault(row.TlsSecret, "<none>")}
+		if options.AllNamespaces {
+			columns = append([]string{row.Namespace}, columns...)
+		}
+		if options.Wide {
+			columns = append(columns, valueOrDefault(row.IngressClass, "<none>"))
+		}
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// printRulesJson prints the flattened rules as JSON array, an empty list is printed as empty array.
+func printRulesJson(out io.Writer, rows []service.IngressRuleRow) error {
+	if rows == nil {
+		rows = []service.IngressRuleRow{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// printRulesYaml prints the flattened rules as YAML list, an empty list is printed as empty list.
+func printRulesYaml(out io.Writer, rows []service.IngressRuleRow) error {
+	if rows == nil {
+		rows = []service.IngressRuleRow{}
+	}
+	data, err := yaml.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func valueOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
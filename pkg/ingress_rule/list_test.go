@@ -0,0 +1,34 @@
+package ingress_rule
+
+import (
+	"bytes"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintRules(t *testing.T) {
+	rows := []service.IngressRuleRow{
+		{Namespace: "default", Ingress: "my-ingress", Host: "foo.com", Path: "/", PathType: "Prefix", Service: "foo", Port: "80", TlsSecret: "foo-tls"},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printRulesJson(out, rows))
+	assert.JSONEq(t, `[{"namespace":"default","ingress":"my-ingress","host":"foo.com","path":"/","pathType":"Prefix","service":"foo","port":"80","tlsSecret":"foo-tls"}]`, out.String())
+
+	out.Reset()
+	assert.NoError(t, printRulesYaml(out, rows))
+	assert.Equal(t, `- host: foo.com
+  ingress: my-ingress
+  namespace: default
+  path: /
+  pathType: Prefix
+  port: "80"
+  service: foo
+  tlsSecret: foo-tls
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, printRulesJson(out, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
@@ -0,0 +1,132 @@
+package ingress_rule
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/manifest"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"os"
+)
+
+// runManifest applies the command to an ingress of a manifest file instead of the cluster.
+// Messages are written to stderr since the resulting manifest is written to stdout unless it is written back to the file.
+func runManifest(configFlags *genericclioptions.ConfigFlags, options *Options) error {
+	if options.dryRunStrategy() == service.DryRunServer {
+		return errors.New("server dry run is not supported for manifest files")
+	}
+
+	data, err := readManifest(options.Filename)
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	namespace := ""
+	if configFlags.Namespace != nil {
+		namespace = *configFlags.Namespace
+	}
+
+	index, ingress := m.Ingress(options.IngressName, namespace)
+	var live *networking.Ingress
+	if ingress != nil {
+		live = ingress.DeepCopy()
+	}
+
+	unchanged := false
+	if options.Set {
+		rule := service.CreateIngressRuleWithBackend(options.Host, options.Path, options.PathType, options.Backend())
+		created := ingress == nil
+		if created {
+			ingress = service.NewIngress(options.IngressName, options.IngressClassName)
+			ingress.Namespace = namespace
+		}
+
+		err = service.ApplyAddRuleWithIssuer(ingress, rule, options.TlsSecret, options.Overwrite, options.CertManagerIssuer())
+		if err == service.ErrIngressRuleAlreadyExists {
+			fmt.Fprintln(os.Stderr, "Doing nothing: Ingress rule already exists")
+			// keep the manifest untouched, the dry-run still prints the (empty) diff
+			unchanged = true
+			ingress = live
+		} else if err != nil {
+			return withConflictHint(err)
+		} else {
+			if created {
+				m.AppendIngress(ingress)
+			} else {
+				m.UpdateIngress(index, ingress)
+			}
+			printAddRuleResult(os.Stderr, options, created)
+		}
+	} else if options.Delete {
+		if ingress == nil {
+			return fmt.Errorf("ingress '%s' not found in manifest", options.IngressName)
+		}
+
+		empty, tlsChanges, err := service.ApplyDeleteRule(ingress, options.RuleSelector())
+		if err != nil {
+			return err
+		}
+
+		if empty {
+			// delete ingress when the last rule is removed
+			ingress = nil
+			m.DeleteIngress(index)
+		} else {
+			m.UpdateIngress(index, ingress)
+		}
+		printDeleteRuleResult(os.Stderr, options, empty, tlsChanges)
+	}
+
+	if options.dryRunStrategy() == service.DryRunClient {
+		diff, err := service.IngressDiff(live, ingress)
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+		return nil
+	}
+	if unchanged && options.InPlace {
+		return nil
+	}
+
+	return writeManifest(m, options)
+}
+
+func readManifest(filename string) ([]byte, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return data, nil
+}
+
+// writeManifest writes the manifest back to the file if requested, otherwise the manifest is written to stdout.
+func writeManifest(m *manifest.Manifest, options *Options) error {
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if !options.InPlace {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	info, err := os.Stat(options.Filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(options.Filename, data, info.Mode().Perm())
+}
@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"regexp"
+	"sigs.k8s.io/yaml"
+)
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// Manifest is a multi document yaml manifest.
+// Documents which are not modified are written back byte by byte.
+type Manifest struct {
+	documents []*document
+}
+
+type document struct {
+	// separator is the separator line preceding the document, empty for the first document
+	separator string
+	raw       []byte
+	ingress   *networking.Ingress
+	modified  bool
+	deleted   bool
+}
+
+type typeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+// Parse splits the data into documents and decodes all networking.k8s.io/v1 ingresses.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+
+	separators := documentSeparator.FindAllIndex(data, -1)
+	start := 0
+	separator := ""
+	for _, loc := range append(separators, []int{len(data), len(data)}) {
+		d := &document{separator: separator, raw: data[start:loc[0]]}
+		if err := d.decode(); err != nil {
+			return nil, fmt.Errorf("failed to parse document %d: %w", len(m.documents)+1, err)
+		}
+		m.documents = append(m.documents, d)
+
+		separator = string(data[loc[0]:loc[1]])
+		start = loc[1]
+	}
+
+	return m, nil
+}
+
+func (d *document) decode() error {
+	meta := typeMeta{}
+	if err := yaml.Unmarshal(d.raw, &meta); err != nil {
+		return err
+	}
+	if meta.Kind != "Ingress" || meta.APIVersion != networking.SchemeGroupVersion.String() {
+		return nil
+	}
+
+	d.ingress = &networking.Ingress{}
+	return yaml.UnmarshalStrict(d.raw, d.ingress)
+}
+
+// Ingress returns a copy of the ingress with the given name and its index.
+// Ingresses without namespace match every namespace, an empty namespace matches every ingress.
+// Returns -1 and nil if there is no such ingress.
+func (m *Manifest) Ingress(name string, namespace string) (int, *networking.Ingress) {
+	for i, d := range m.documents {
+		if d.ingress == nil || d.deleted || d.ingress.Name != name {
+			continue
+		}
+		if namespace != "" && d.ingress.Namespace != "" && d.ingress.Namespace != namespace {
+			continue
+		}
+		return i, d.ingress.DeepCopy()
+	}
+	return -1, nil
+}
+
+// Ingresses returns copies of all ingresses of the manifest.
+func (m *Manifest) Ingresses() []*networking.Ingress {
+	var ingresses []*networking.Ingress
+	for _, d := range m.documents {
+		if d.ingress != nil && !d.deleted {
+			ingresses = append(ingresses, d.ingress.DeepCopy())
+		}
+	}
+	return ingresses
+}
+
+// UpdateIngress replaces the ingress at the index.
+func (m *Manifest) UpdateIngress(index int, ingress *networking.Ingress) {
+	m.documents[index].ingress = ingress
+	m.documents[index].modified = true
+}
+
+// DeleteIngress removes the document of the ingress at the index.
+func (m *Manifest) DeleteIngress(index int) {
+	m.documents[index].deleted = true
+}
+
+// AppendIngress adds the ingress as new document at the end of the manifest.
+func (m *Manifest) AppendIngress(ingress *networking.Ingress) {
+	m.documents = append(m.documents, &document{
+		separator: "---",
+		ingress:   ingress,
+		modified:  true,
+	})
+}
+
+// Bytes serializes the manifest, only modified documents are encoded again.
+func (m *Manifest) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, d := range m.documents {
+		if d.deleted {
+			continue
+		}
+
+		raw := d.raw
+		if d.modified {
+			encoded, err := MarshalIngress(d.ingress)
+			if err != nil {
+				return nil, err
+			}
+			raw = encoded
+			if d.separator != "" {
+				raw = append([]byte("\n"), raw...)
+			}
+		}
+
+		// a separator has to start at the beginning of a line
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(d.separator)
+		buf.Write(raw)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MarshalIngress encodes the ingress as yaml without the empty status and creation timestamp.
+func MarshalIngress(ingress *networking.Ingress) ([]byte, error) {
+	ingress = ingress.DeepCopy()
+	ingress.APIVersion = networking.SchemeGroupVersion.String()
+	ingress.Kind = "Ingress"
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ingress)
+	if err != nil {
+		return nil, err
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok && metadata["creationTimestamp"] == nil {
+		delete(metadata, "creationTimestamp")
+	}
+	if ingress.Status.LoadBalancer.Ingress == nil {
+		delete(obj, "status")
+	}
+
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,139 @@
+package manifest
+
+import (
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+const service = `# leading comment
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo   # trailing comment
+spec:
+  ports:
+  - port: 80
+`
+
+const ingress = `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: foo
+  namespace: default
+spec:
+  rules:
+  - host: foo.com
+    http:
+      paths:
+      - backend:
+          service:
+            name: service-foo
+            port:
+              number: 80
+        path: /
+        pathType: Prefix
+`
+
+const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  key: value
+`
+
+func TestManifest_UnmodifiedDocumentsArePreserved(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"single document", ingress},
+		{"multiple documents", service + "---\n" + ingress + "--- # comment\n" + configMap},
+		{"leading separator", "---\n" + service + "---\n" + ingress},
+		{"trailing separator", service + "---\n" + ingress + "---\n"},
+		{"empty document", service + "---\n---\n" + ingress},
+		{"empty manifest", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := Parse([]byte(test.data))
+			assert.NoError(t, err)
+
+			out, err := m.Bytes()
+			assert.NoError(t, err)
+			assert.Equal(t, test.data, string(out))
+		})
+	}
+}
+
+func TestManifest_Ingress(t *testing.T) {
+	m, err := Parse([]byte(service + "---\n" + ingress + "---\n" + configMap))
+	assert.NoError(t, err)
+
+	index, found := m.Ingress("foo", "")
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "service-foo", found.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+	index, found = m.Ingress("foo", "default")
+	assert.Equal(t, 1, index)
+	assert.NotNil(t, found)
+
+	index, found = m.Ingress("foo", "other")
+	assert.Equal(t, -1, index)
+	assert.Nil(t, found)
+
+	index, found = m.Ingress("bar", "")
+	assert.Equal(t, -1, index)
+	assert.Nil(t, found)
+
+	assert.Len(t, m.Ingresses(), 1)
+}
+
+func TestManifest_ModifyIngress(t *testing.T) {
+	m, err := Parse([]byte(service + "---\n" + ingress + "---\n" + configMap))
+	assert.NoError(t, err)
+
+	index, found := m.Ingress("foo", "")
+	found.Spec.Rules[0].Host = "bar.com"
+	m.UpdateIngress(index, found)
+
+	out, err := m.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, service+"---\n"+ingressWithHost("bar.com")+"---\n"+configMap, string(out))
+
+	m.DeleteIngress(index)
+	out, err = m.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, service+"---\n"+configMap, string(out))
+
+	m.AppendIngress(&networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       found.Spec,
+	})
+	out, err = m.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, service+"---\n"+configMap+"---\n"+ingressWithHost("bar.com"), string(out))
+}
+
+func ingressWithHost(host string) string {
+	return `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: foo
+  namespace: default
+spec:
+  rules:
+  - host: ` + host + `
+    http:
+      paths:
+      - backend:
+          service:
+            name: service-foo
+            port:
+              number: 80
+        path: /
+        pathType: Prefix
+`
+}
@@ -0,0 +1,94 @@
+package ingress_rule
+
+import (
+	"context"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"testing"
+	"time"
+)
+
+func TestRunPlugin_manifestCertManagerIssuer(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ingress.yaml")
+	assert.NoError(t, os.WriteFile(filename, []byte{}, 0o644))
+
+	options := &Options{
+		IngressName: "my-ingress",
+		Host:        "foo.com",
+		Path:        "/",
+		PathType:    networking.PathTypePrefix,
+		Set:         true,
+		Filename:    filename,
+		InPlace:     true,
+		Target:      TargetIngress,
+		BackendOptions: BackendOptions{
+			ServiceName: "foo",
+			PortNumber:  80,
+		},
+		CertManagerOptions: CertManagerOptions{Issuer: "letsencrypt"},
+	}
+	assert.NoError(t, RunPlugin(context.TODO(), genericclioptions.NewConfigFlags(false), options))
+
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	ingress := &networking.Ingress{}
+	assert.NoError(t, yaml.Unmarshal(data, ingress))
+	assert.Equal(t, "letsencrypt", ingress.Annotations[service.CertManagerIssuerAnnotation])
+	assert.Equal(t, []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "foo-com-tls"}}, ingress.Spec.TLS)
+}
+
+func TestRunPlugin_manifestExistingRule(t *testing.T) {
+	manifest := `# managed by hand
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: my-ingress
+spec:
+  rules:
+  - host: foo.com
+    http:
+      paths:
+      - backend:
+          service:
+            name: foo
+            port:
+              number: 80
+        path: /
+        pathType: Prefix
+`
+	for _, dryRun := range []string{DryRunNone, DryRunClient} {
+		t.Run("dry-run="+dryRun, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "ingress.yaml")
+			assert.NoError(t, os.WriteFile(filename, []byte(manifest), 0o644))
+			modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+			assert.NoError(t, os.Chtimes(filename, modified, modified))
+
+			options := &Options{
+				IngressName: "my-ingress",
+				Host:        "foo.com",
+				Path:        "/",
+				PathType:    networking.PathTypePrefix,
+				Set:         true,
+				Filename:    filename,
+				InPlace:     true,
+				Target:      TargetIngress,
+				BackendOptions: BackendOptions{
+					ServiceName: "foo",
+					PortNumber:  80,
+				},
+			}
+			options.DryRun = dryRun
+			assert.NoError(t, RunPlugin(context.TODO(), genericclioptions.NewConfigFlags(false), options))
+
+			// the file must not be rewritten when nothing changed
+			info, err := os.Stat(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, modified, info.ModTime())
+		})
+	}
+}
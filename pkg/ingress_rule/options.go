@@ -1,18 +1,243 @@
 package ingress_rule
 
 import (
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
 	networking "k8s.io/api/networking/v1"
+	"os"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"time"
 )
 
+const DryRunNone = "none"
+const DryRunClient = "client"
+const DryRunServer = "server"
+
+// TlsAuto selects a tls secret whose certificate covers the host instead of a secret name.
+const TlsAuto = "auto"
+
+const TlsAdd = "add"
+const TlsRemove = "remove"
+const TlsRotate = "rotate"
+
+const TargetIngress = "ingress"
+const TargetHTTPRoute = "httproute"
+
 type Options struct {
-	IngressName      string
-	IngressClassName string
-	Host             string
-	Path             string
-	Delete           bool
-	Set              bool
-	PathType         networking.PathType
+	IngressName       string
+	IngressClassName  string
+	Host              string
+	MatchHost         bool // select rules by host on delete, allows to select the rule without host
+	Path              string
+	Delete            bool
+	Set               bool
+	PathType          networking.PathType
+	TlsSecret         string
+	SkipTlsValidation bool // use the tls secret without checking it and its certificate
+	NoVerifyBackend   bool // write the rule without checking that the backend service and port exist
+	Overwrite         bool
+	Filename          string // edit the ingress in a manifest file instead of the cluster, "-" reads from stdin
+	InPlace           bool   // write the manifest back to the file instead of stdout
+	Target            string // kind of the resource managing the rules, either TargetIngress or TargetHTTPRoute
+	BackendOptions
+	GatewayOptions
+	TlsSecretOptions
+	CertManagerOptions
+	UpdateOptions
+}
+
+// BackendOptions select the backend of a rule, either a service or a resource.
+type BackendOptions struct {
 	ServiceName      string
 	PortNumber       int32
-	TlsSecret        string
+	PortName         string
+	ResourceApiGroup string
+	ResourceKind     string
+	ResourceName     string
+}
+
+// TlsSecretOptions select how the tls secret of a host is provided instead of referencing an existing secret.
+// Self-signed certificates are only supported by set.
+type TlsSecretOptions struct {
+	SelfSigned bool // generate a self-signed certificate for the host
+	Validity   time.Duration
+	KeyType    service.KeyType
+	CertFile   string // create or update the tls secret from the PEM encoded certificate and key files
+	KeyFile    string
+}
+
+// CertManagerOptions let cert-manager issue the certificate of the host.
+type CertManagerOptions struct {
+	Issuer             string
+	ClusterIssuer      bool
+	WaitForCertificate bool // wait until the Certificate created by cert-manager is ready
+	WaitTimeout        time.Duration
+}
+
+// CertManagerIssuer returns the issuer annotated on the ingress.
+func (o *CertManagerOptions) CertManagerIssuer() service.CertManagerIssuer {
+	return service.CertManagerIssuer{Name: o.Issuer, Cluster: o.ClusterIssuer}
+}
+
+// GatewayOptions select the gateway HTTPRoutes are attached to.
+type GatewayOptions struct {
+	Gateway          string
+	GatewayNamespace string
+	SectionName      string // listener of the gateway
+}
+
+// UpdateOptions are shared by all commands modifying ingresses.
+type UpdateOptions struct {
+	DryRun       string
+	Retries      int
+	RetryTimeout time.Duration
+}
+
+// ServicePort returns the backend service port either referenced by number or by name.
+func (o *BackendOptions) ServicePort() networking.ServiceBackendPort {
+	return networking.ServiceBackendPort{
+		Name:   o.PortName,
+		Number: o.PortNumber,
+	}
+}
+
+// Backend returns the backend referencing either the service or the resource.
+func (o *BackendOptions) Backend() networking.IngressBackend {
+	if o.ResourceName != "" {
+		return service.CreateResourceBackend(o.ResourceApiGroup, o.ResourceKind, o.ResourceName)
+	}
+	return service.CreateServiceBackend(o.ServiceName, o.ServicePort())
+}
+
+// RuleSelector returns the selector for rules to delete.
+func (o *Options) RuleSelector() service.RuleSelector {
+	selector := service.RuleSelector{
+		Path:        o.Path,
+		ServiceName: o.ServiceName,
+		ServicePort: o.ServicePort(),
+	}
+	if o.MatchHost {
+		host := o.Host
+		selector.Host = &host
+	}
+	if o.PathType != "" {
+		pathType := o.PathType
+		selector.PathType = &pathType
+	}
+	return selector
+}
+
+// ParentRefs returns the gateway the HTTPRoute is attached to, no gateway results in no parent refs.
+func (o *GatewayOptions) ParentRefs() []gateway.ParentRef {
+	if o.Gateway == "" {
+		return nil
+	}
+	return []gateway.ParentRef{service.NewGatewayParentRef(o.Gateway, o.GatewayNamespace, o.SectionName)}
+}
+
+// resourceKind returns the kind of the resource managing the rules for messages to the user.
+func (o *Options) resourceKind() string {
+	if o.Target == TargetHTTPRoute {
+		return "HTTPRoute"
+	}
+	return "ingress"
+}
+
+// updateConfigurable is implemented by the services modifying resources.
+type updateConfigurable interface {
+	SetRetry(retries int, timeout time.Duration)
+	SetDryRun(strategy service.DryRunStrategy, out io.Writer)
+}
+
+// configure applies the dry run and retry options to the service.
+func (o *UpdateOptions) configure(s updateConfigurable) {
+	s.SetRetry(o.Retries, o.RetryTimeout)
+	if o.dryRunStrategy() != service.DryRunNone {
+		s.SetDryRun(o.dryRunStrategy(), os.Stdout)
+	}
+}
+
+// dryRunStrategy maps the dry run option to the strategy of the service package.
+func (o *UpdateOptions) dryRunStrategy() service.DryRunStrategy {
+	switch o.DryRun {
+	case DryRunClient:
+		return service.DryRunClient
+	case DryRunServer:
+		return service.DryRunServer
+	default:
+		return service.DryRunNone
+	}
+}
+
+// dryRunSuffix returns a suffix for messages to the user indicating that nothing has been persisted.
+func (o *UpdateOptions) dryRunSuffix() string {
+	if o.dryRunStrategy() == service.DryRunNone {
+		return ""
+	}
+	return fmt.Sprintf(" (dry run: %s)", o.DryRun)
+}
+
+type SyncOptions struct {
+	Prune service.PruneMode
+	UpdateOptions
+}
+
+type DefaultBackendOptions struct {
+	IngressName      string
+	IngressClassName string
+	Unset            bool
+	BackendOptions
+	UpdateOptions
+}
+
+type TlsOptions struct {
+	IngressName       string
+	Action            string // either TlsAdd, TlsRemove or TlsRotate
+	Hosts             []string
+	Secret            string // secret of the hosts, for TlsRemove an empty secret selects every secret
+	SkipTlsValidation bool   // use the tls secret without checking it and its certificate
+	TlsSecretOptions
+	TlsBulkOptions
+	UpdateOptions
+}
+
+// TlsBulkOptions select the ingresses whose tls secret is rotated by TlsRotate without ingress name.
+type TlsBulkOptions struct {
+	From          string
+	To            string
+	AllNamespaces bool
+	LabelSelector string
+}
+
+// ExposeOptions select the workload exposed by a service and the rule routing to the service.
+// The service name and port of the embedded options select the service, an empty port selects the port of the service automatically.
+type ExposeOptions struct {
+	WorkloadKind string // either service.WorkloadDeployment, service.WorkloadStatefulSet or service.WorkloadDaemonSet
+	WorkloadName string
+	TargetPort   string // container port targeted by a new service, either a number or a name
+	Options
+}
+
+type ConvertOptions struct {
+	IngressName string
+	To          string
+	Apply       bool // apply the resources instead of writing them to stdout
+	GatewayOptions
+	UpdateOptions
+}
+
+const CertsOutputTable = "table"
+const CertsOutputJson = "json"
+const CertsOutputPrometheus = "prometheus"
+
+type CertsOptions struct {
+	AllNamespaces bool
+	Output        string // either CertsOutputTable, CertsOutputJson or CertsOutputPrometheus
+}
+
+type ListOptions struct {
+	IngressName   string
+	AllNamespaces bool
+	Wide          bool
 }
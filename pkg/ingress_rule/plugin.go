@@ -2,38 +2,90 @@ package ingress_rule
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
+	"os"
+	"strings"
 )
 
 func RunPlugin(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *Options) error {
-	config, err := configFlags.ToRESTConfig()
-	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	if options.Set && options.Issuer != "" {
+		// the secret is issued by cert-manager, its name is derived from the host for manifest files and the cluster alike
+		options.TlsSecret = service.CertManagerSecretName(options.Host)
+	}
+	if options.Filename != "" {
+		return runManifest(configFlags, options)
+	}
+	if options.Target == TargetHTTPRoute {
+		return runHTTPRoute(ctx, configFlags, options)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := newClientset(configFlags)
 	if err != nil {
-		return fmt.Errorf("failed to create clientset: %w", err)
+		return err
 	}
 
-	// check that namespace exits
-	namespace, _, err := configFlags.ToRawKubeConfigLoader().Namespace()
+	namespace, err := existingNamespace(ctx, configFlags, clientset)
 	if err != nil {
 		return err
 	}
 
-	if _, err = clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
-		return err
+	if options.Set {
+		if err = selectServicePort(ctx, clientset, namespace, options); err != nil {
+			return err
+		}
+	}
+	if options.Set && !options.NoVerifyBackend {
+		if err = verifyBackend(ctx, clientset, namespace, options); err != nil {
+			return err
+		}
 	}
 
-	// create new IngressService and execute command
 	ingressService := service.NewIngressService(clientset, namespace, options.IngressName, options.IngressClassName)
+	options.configure(ingressService)
+
+	if options.Set && options.SelfSigned {
+		// the secret is only created or renewed if the rule can be added, an existing rule referencing it is fine
+		rule := service.CreateIngressRuleWithBackend(options.Host, options.Path, options.PathType, options.Backend())
+		if err = ingressService.CheckAddRule(ctx, rule, service.SelfSignedSecretName(options.Host), options.Overwrite); err != nil && err != service.ErrIngressRuleAlreadyExists {
+			return withConflictHint(err)
+		}
+		if options.TlsSecret, err = ensureSelfSignedSecret(ctx, clientset, namespace, options.Host, options); err != nil {
+			return err
+		}
+	} else if options.Set && options.CertFile != "" {
+		// the secret may be used by other ingresses, it is only written if the rule can be added.
+		// An existing rule already referencing the secret is fine, the certificate of the secret is updated then.
+		rule := service.CreateIngressRuleWithBackend(options.Host, options.Path, options.PathType, options.Backend())
+		if err = ingressService.CheckAddRule(ctx, rule, options.TlsSecret, options.Overwrite); err != nil && err != service.ErrIngressRuleAlreadyExists {
+			return withConflictHint(err)
+		}
+		if err = createTlsSecretFromFiles(ctx, clientset, namespace, options.TlsSecret, []string{options.Host}, &options.TlsSecretOptions, &options.UpdateOptions); err != nil {
+			return err
+		}
+	} else if options.Set && options.TlsSecret == TlsAuto {
+		if options.TlsSecret, err = findTlsSecret(ctx, clientset, namespace, options.Host); err != nil {
+			return err
+		}
+	} else if options.Set && options.TlsSecret != "" && options.Issuer == "" && !options.SkipTlsValidation {
+		// secrets of cert-manager are not validated, they do not exist until the certificate is issued for the annotated ingress
+		if err = validateTlsSecret(ctx, clientset, namespace, options.TlsSecret, []string{options.Host}); err != nil {
+			return err
+		}
+	}
+
 	if options.Set {
-		return addRule(ctx, ingressService, options)
+		ingressService.SetCertManagerIssuer(options.CertManagerIssuer())
+		if err = addRule(ctx, ingressService, options); err != nil || !options.WaitForCertificate {
+			return err
+		}
+		return waitForCertificate(ctx, configFlags, namespace, options.TlsSecret, options.WaitTimeout)
 	} else if options.Delete {
 		return deleteRule(ctx, ingressService, options)
 	}
@@ -41,40 +93,107 @@ func RunPlugin(ctx context.Context, configFlags *genericclioptions.ConfigFlags,
 	return nil
 }
 
+func newClientset(configFlags *genericclioptions.ConfigFlags) (*kubernetes.Clientset, error) {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return clientset, nil
+}
+
+// existingNamespace returns the namespace selected by the kubeconfig or the command line flags and checks that it exists.
+func existingNamespace(ctx context.Context, configFlags *genericclioptions.ConfigFlags, clientset *kubernetes.Clientset) (string, error) {
+	namespace, _, err := configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		return "", err
+	}
+
+	return namespace, nil
+}
+
 func addRule(ctx context.Context, ingressService *service.IngressService, options *Options) error {
-	backendRule := service.CreateIngressRule(options.Host, options.Path, options.PathType, options.ServiceName, options.PortNumber)
+	backendRule := service.CreateIngressRuleWithBackend(options.Host, options.Path, options.PathType, options.Backend())
 
-	created, err := ingressService.AddRule(ctx, backendRule, options.TlsSecret)
+	created, err := ingressService.AddRule(ctx, backendRule, options.TlsSecret, options.Overwrite)
 	if err == service.ErrIngressRuleAlreadyExists {
 		fmt.Println("Doing nothing: Ingress rule already exists")
 		return nil
 	} else if err != nil {
-		return err
+		return withConflictHint(err)
 	}
 
-	fmt.Printf("Added rule for host '%s' with path '%s' (path type: '%s') for service '%s' (port: '%d') to ingress '%s'\n",
-		options.Host, options.Path, options.PathType, options.ServiceName, options.PortNumber, options.IngressName)
-	if created {
-		fmt.Printf("Created ingress '%s'\n", options.IngressName)
+	printAddRuleResult(os.Stdout, options, created)
+	return nil
+}
+
+// withConflictHint adds a hint how to resolve backend and tls conflicts to the error.
+func withConflictHint(err error) error {
+	var backendConflict *service.BackendConflictError
+	if errors.As(err, &backendConflict) {
+		return fmt.Errorf("%w; use --overwrite to replace the backend", err)
 	}
+	if errors.Is(err, service.ErrTlsConfigurationAlreadyExists) {
+		return fmt.Errorf("%w; use 'tls rotate' to change the secret", err)
+	}
+	return err
+}
 
-	return nil
+func printAddRuleResult(out io.Writer, options *Options, created bool) {
+	fmt.Fprintf(out, "Added rule for host '%s' with path '%s' (path type: '%s') for %s to %s '%s'%s\n",
+		options.Host, options.Path, options.PathType, service.FormatBackend(options.Backend()), options.resourceKind(), options.IngressName, options.dryRunSuffix())
+	if created {
+		fmt.Fprintf(out, "Created %s '%s'%s\n", options.resourceKind(), options.IngressName, options.dryRunSuffix())
+	}
 }
 
 func deleteRule(ctx context.Context, ingressService *service.IngressService, options *Options) error {
-	deleted, err := ingressService.DeleteRule(ctx, options.ServiceName, options.PortNumber)
+	deleted, tlsChanges, err := ingressService.DeleteRule(ctx, options.RuleSelector())
 	if err != nil {
 		return err
 	}
+
+	printDeleteRuleResult(os.Stdout, options, deleted, tlsChanges)
+	return nil
+}
+
+func printDeleteRuleResult(out io.Writer, options *Options, deleted bool, tlsChanges []service.TlsChange) {
 	if deleted {
-		fmt.Printf("Deleted ingress '%s'\n", options.IngressName)
+		fmt.Fprintf(out, "Deleted %s '%s'%s\n", options.resourceKind(), options.IngressName, options.dryRunSuffix())
 	}
 
-	if options.PortNumber != 0 {
-		fmt.Printf("Removed rule(s) for service '%s' (port: '%d') from ingress '%s'\n", options.ServiceName, options.PortNumber, options.IngressName)
-	} else {
-		fmt.Printf("Removed rule(s) for service '%s' from ingress '%s'\n", options.ServiceName, options.IngressName)
+	fmt.Fprintf(out, "Removed rule(s) for %s from %s '%s'%s\n", describeSelector(options.RuleSelector()), options.resourceKind(), options.IngressName, options.dryRunSuffix())
+	for _, change := range tlsChanges {
+		fmt.Fprintf(out, "Updated tls of %s '%s': %s%s\n", options.resourceKind(), options.IngressName, change, options.dryRunSuffix())
 	}
+}
 
-	return nil
+// describeSelector returns a printable description of the criteria of a selector.
+func describeSelector(selector service.RuleSelector) string {
+	var criteria []string
+	if selector.Host != nil {
+		criteria = append(criteria, fmt.Sprintf("host '%s'", *selector.Host))
+	}
+	if selector.Path != "" {
+		criteria = append(criteria, fmt.Sprintf("path '%s'", selector.Path))
+	}
+	if selector.PathType != nil {
+		criteria = append(criteria, fmt.Sprintf("path type '%s'", *selector.PathType))
+	}
+	if selector.ServiceName != "" {
+		criteria = append(criteria, fmt.Sprintf("service '%s'", selector.ServiceName))
+	}
+	if selector.ServicePort != (networking.ServiceBackendPort{}) {
+		criteria = append(criteria, fmt.Sprintf("port '%s'", service.FormatServicePort(selector.ServicePort)))
+	}
+	return strings.Join(criteria, " and ")
 }
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	clientdiscovery "k8s.io/client-go/kubernetes/typed/discovery/v1"
+	"strings"
+)
+
+// BackendService reads the services and endpoints rules route to.
+type BackendService struct {
+	kubeService       clientcore.ServiceInterface
+	kubeEndpoints     clientcore.EndpointsInterface
+	kubeEndpointSlice clientdiscovery.EndpointSliceInterface
+}
+
+func NewBackendService(clientset *kubernetes.Clientset, namespace string) *BackendService {
+	return &BackendService{
+		kubeService:       clientset.CoreV1().Services(namespace),
+		kubeEndpoints:     clientset.CoreV1().Endpoints(namespace),
+		kubeEndpointSlice: clientset.DiscoveryV1().EndpointSlices(namespace),
+	}
+}
+
+// VerifyServiceBackend checks that the service exists and defines the port.
+// Returns a warning if the service has no ready endpoints or its endpoints can not be read and an error
+func (b *BackendService) VerifyServiceBackend(ctx context.Context, name string, port networking.ServiceBackendPort) ([]string, error) {
+	service, err := b.kubeService.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: service '%s' does not exist", ErrBackendServiceNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = VerifyServicePort(service, port); err != nil {
+		return nil, err
+	}
+	if service.Spec.Type == core.ServiceTypeExternalName {
+		// external name services have no endpoints
+		return nil, nil
+	}
+
+	ready, err := b.readyEndpoints(ctx, name)
+	if err != nil {
+		// reading endpoints may be forbidden although the service is readable, the endpoints are only checked for a warning
+		return []string{fmt.Sprintf("could not check endpoints of service '%s': %s", name, err)}, nil
+	}
+	if ready == 0 {
+		return []string{fmt.Sprintf("service '%s' has no ready endpoints", name)}, nil
+	}
+	return nil, nil
+}
+
+// SelectServicePort selects the port of the service used when no port has been supplied.
+func (b *BackendService) SelectServicePort(ctx context.Context, name string) (networking.ServiceBackendPort, error) {
+	service, err := b.kubeService.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return networking.ServiceBackendPort{}, fmt.Errorf("%w: service '%s' does not exist", ErrBackendServiceNotFound, name)
+	} else if err != nil {
+		return networking.ServiceBackendPort{}, err
+	}
+
+	return SelectServicePort(service)
+}
+
+// readyEndpoints counts the ready endpoints of the service, clusters without EndpointSlices fall back to Endpoints.
+func (b *BackendService) readyEndpoints(ctx context.Context, name string) (int, error) {
+	sliceList, err := b.kubeEndpointSlice.List(ctx, meta.ListOptions{LabelSelector: discovery.LabelServiceName + "=" + name})
+	if err == nil {
+		return ReadyEndpointSliceEndpoints(sliceList.Items), nil
+	} else if !apierror.IsNotFound(err) {
+		return 0, err
+	}
+
+	endpoints, err := b.kubeEndpoints.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	ready := 0
+	for _, subset := range endpoints.Subsets {
+		ready += len(subset.Addresses)
+	}
+	return ready, nil
+}
+
+// VerifyServicePort checks that the service defines the port number or port name without accessing the cluster.
+// Ports of external name services are not checked since they are not required to be defined.
+func VerifyServicePort(service *core.Service, port networking.ServiceBackendPort) error {
+	if service.Spec.Type == core.ServiceTypeExternalName && len(service.Spec.Ports) == 0 {
+		return nil
+	}
+
+	var defined []string
+	for _, servicePort := range service.Spec.Ports {
+		if (port.Name != "" && servicePort.Name == port.Name) || (port.Name == "" && servicePort.Port == port.Number) {
+			return nil
+		}
+		defined = append(defined, FormatServicePortSpec(servicePort))
+	}
+
+	if len(defined) == 0 {
+		defined = []string{"<none>"}
+	}
+	return fmt.Errorf("%w: service '%s' does not define port '%s' (defined: %s)",
+		ErrBackendPortNotFound, service.Name, FormatServicePort(port), strings.Join(defined, ", "))
+}
+
+// SelectServicePort selects the only port of the service or else the only port named "http" or "https" without accessing the cluster.
+// The port is selected by number. Returns ErrBackendPortAmbiguous listing the ports of the service if the port is ambiguous
+func SelectServicePort(service *core.Service) (networking.ServiceBackendPort, error) {
+	ports := service.Spec.Ports
+	if len(ports) == 1 {
+		return networking.ServiceBackendPort{Number: ports[0].Port}, nil
+	}
+
+	var webPorts []core.ServicePort
+	var defined []string
+	for _, port := range ports {
+		if port.Name == "http" || port.Name == "https" {
+			webPorts = append(webPorts, port)
+		}
+		defined = append(defined, FormatServicePortSpec(port))
+	}
+	if len(webPorts) == 1 {
+		return networking.ServiceBackendPort{Number: webPorts[0].Port}, nil
+	}
+
+	if len(defined) == 0 {
+		defined = []string{"<none>"}
+	}
+	return networking.ServiceBackendPort{}, fmt.Errorf("%w: select a port of service '%s' (defined: %s)",
+		ErrBackendPortAmbiguous, service.Name, strings.Join(defined, ", "))
+}
+
+// ReadyEndpointSliceEndpoints counts the endpoints of the slices which are ready, endpoints with unknown readiness are counted as ready.
+func ReadyEndpointSliceEndpoints(slices []discovery.EndpointSlice) int {
+	ready := 0
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	return ready
+}
+
+// FormatServicePortSpec returns a printable representation of a port of a service, e.g. "http (80)" or "80".
+func FormatServicePortSpec(port core.ServicePort) string {
+	if port.Name != "" {
+		return fmt.Sprintf("%s (%d)", port.Name, port.Port)
+	}
+	return fmt.Sprintf("%d", port.Port)
+}
+
+var ErrBackendServiceNotFound = errors.New("backend service not found")
+var ErrBackendPortNotFound = errors.New("backend port not found")
+var ErrBackendPortAmbiguous = errors.New("no port supplied and the service port is ambiguous")
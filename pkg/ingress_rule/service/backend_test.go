@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestVerifyServicePort(t *testing.T) {
+	service := testService("foo", core.ServicePort{Name: "http", Port: 80}, core.ServicePort{Port: 8443})
+
+	assert.NoError(t, VerifyServicePort(service, networking.ServiceBackendPort{Number: 80}))
+	assert.NoError(t, VerifyServicePort(service, networking.ServiceBackendPort{Number: 8443}))
+	assert.NoError(t, VerifyServicePort(service, networking.ServiceBackendPort{Name: "http"}))
+
+	err := VerifyServicePort(service, networking.ServiceBackendPort{Number: 8080})
+	assert.ErrorIs(t, err, ErrBackendPortNotFound)
+	assert.Contains(t, err.Error(), "service 'foo' does not define port '8080' (defined: http (80), 8443)")
+
+	err = VerifyServicePort(service, networking.ServiceBackendPort{Name: "https"})
+	assert.ErrorIs(t, err, ErrBackendPortNotFound)
+
+	externalName := testService("external")
+	externalName.Spec.Type = core.ServiceTypeExternalName
+	assert.NoError(t, VerifyServicePort(externalName, networking.ServiceBackendPort{Number: 443}))
+}
+
+func TestSelectServicePort(t *testing.T) {
+	tests := []struct {
+		name          string
+		ports         []core.ServicePort
+		expectedPort  networking.ServiceBackendPort
+		expectedError string
+	}{
+		{
+			name:         "single port",
+			ports:        []core.ServicePort{{Name: "grpc", Port: 9090}},
+			expectedPort: networking.ServiceBackendPort{Number: 9090},
+		},
+		{
+			name:         "single port named http",
+			ports:        []core.ServicePort{{Name: "metrics", Port: 9090}, {Name: "http", Port: 8080}},
+			expectedPort: networking.ServiceBackendPort{Number: 8080},
+		},
+		{
+			name:         "single port named https",
+			ports:        []core.ServicePort{{Name: "https", Port: 8443}, {Name: "metrics", Port: 9090}},
+			expectedPort: networking.ServiceBackendPort{Number: 8443},
+		},
+		{
+			name:          "http and https",
+			ports:         []core.ServicePort{{Name: "http", Port: 80}, {Name: "https", Port: 443}},
+			expectedError: "select a port of service 'foo' (defined: http (80), https (443))",
+		},
+		{
+			name:          "several unnamed ports",
+			ports:         []core.ServicePort{{Port: 80}, {Port: 81}},
+			expectedError: "select a port of service 'foo' (defined: 80, 81)",
+		},
+		{
+			name:          "no port",
+			expectedError: "select a port of service 'foo' (defined: <none>)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port, err := SelectServicePort(testService("foo", test.ports...))
+			if test.expectedError != "" {
+				assert.ErrorIs(t, err, ErrBackendPortAmbiguous)
+				assert.Contains(t, err.Error(), test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedPort, port)
+		})
+	}
+}
+
+func TestBackendService_VerifyServiceBackend(t *testing.T) {
+	ready, notReady := true, false
+	clientset := fake.NewSimpleClientset(
+		testService("foo", core.ServicePort{Name: "http", Port: 80}),
+		testService("bar", core.ServicePort{Name: "http", Port: 80}),
+		testEndpointSlice("foo-abc", "foo", &ready),
+		testEndpointSlice("bar-abc", "bar", &notReady),
+	)
+	backendService := BackendService{
+		kubeService:       clientset.CoreV1().Services("default"),
+		kubeEndpoints:     clientset.CoreV1().Endpoints("default"),
+		kubeEndpointSlice: clientset.DiscoveryV1().EndpointSlices("default"),
+	}
+
+	warnings, err := backendService.VerifyServiceBackend(context.TODO(), "foo", networking.ServiceBackendPort{Name: "http"})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	warnings, err = backendService.VerifyServiceBackend(context.TODO(), "bar", networking.ServiceBackendPort{Number: 80})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"service 'bar' has no ready endpoints"}, warnings)
+
+	_, err = backendService.VerifyServiceBackend(context.TODO(), "baz", networking.ServiceBackendPort{Number: 80})
+	assert.ErrorIs(t, err, ErrBackendServiceNotFound)
+
+	_, err = backendService.VerifyServiceBackend(context.TODO(), "foo", networking.ServiceBackendPort{Number: 8080})
+	assert.ErrorIs(t, err, ErrBackendPortNotFound)
+}
+
+func TestBackendService_VerifyServiceBackend_endpointsForbidden(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testService("foo", core.ServicePort{Name: "http", Port: 80}))
+	clientset.PrependReactor("list", "endpointslices", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierror.NewForbidden(discovery.Resource("endpointslices"), "", nil)
+	})
+	backendService := BackendService{
+		kubeService:       clientset.CoreV1().Services("default"),
+		kubeEndpoints:     clientset.CoreV1().Endpoints("default"),
+		kubeEndpointSlice: clientset.DiscoveryV1().EndpointSlices("default"),
+	}
+
+	warnings, err := backendService.VerifyServiceBackend(context.TODO(), "foo", networking.ServiceBackendPort{Number: 80})
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "could not check endpoints of service 'foo'")
+
+	// the service and port are still verified
+	_, err = backendService.VerifyServiceBackend(context.TODO(), "foo", networking.ServiceBackendPort{Number: 8080})
+	assert.ErrorIs(t, err, ErrBackendPortNotFound)
+}
+
+func testService(name string, ports ...core.ServicePort) *core.Service {
+	return &core.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       core.ServiceSpec{Type: core.ServiceTypeClusterIP, Ports: ports},
+	}
+}
+
+func testEndpointSlice(name string, service string, ready *bool) *discovery.EndpointSlice {
+	return &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{discovery.LabelServiceName: service},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints: []discovery.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, Conditions: discovery.EndpointConditions{Ready: ready}},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"strings"
+	"time"
+)
+
+// CertificateExpiryWarning is the remaining validity below which a certificate is reported as expiring soon.
+const CertificateExpiryWarning = 30 * 24 * time.Hour
+
+// SecretService reads and writes tls secrets.
+// Dry run is supported, but no diff is written to keep the private keys out of the output.
+type SecretService struct {
+	kubeSecret clientcore.SecretInterface
+	updateSettings
+}
+
+func NewSecretService(clientset *kubernetes.Clientset, namespace string) *SecretService {
+	return &SecretService{
+		kubeSecret: clientset.CoreV1().Secrets(namespace),
+	}
+}
+
+// NewTlsSecret returns a new secret of type kubernetes.io/tls containing the PEM encoded certificate and private key.
+func NewTlsSecret(name string, certPEM []byte, keyPEM []byte) *core.Secret {
+	return &core.Secret{
+		TypeMeta: meta.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name: name,
+		},
+		Type: core.SecretTypeTLS,
+		Data: map[string][]byte{
+			core.TLSCertKey:       certPEM,
+			core.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+}
+
+// GetSecret returns the secret or nil if it does not exist.
+func (s *SecretService) GetSecret(ctx context.Context, name string) (*core.Secret, error) {
+	secret, err := s.kubeSecret.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// CreateOrUpdateTlsSecret creates the tls secret or replaces the certificate and private key of the existing secret.
+// An existing secret of another type is not modified.
+// Conflicting concurrent modifications of the secret are retried.
+// Returns if the secret has been created and an error
+func (s *SecretService) CreateOrUpdateTlsSecret(ctx context.Context, name string, certPEM []byte, keyPEM []byte) (created bool, err error) {
+	err = s.retryOnConflict(ctx, func(ctx context.Context) error {
+		created = false
+
+		secret, err := s.kubeSecret.Get(ctx, name, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			created = true
+			if s.dryRun == DryRunClient {
+				return nil
+			}
+			_, err = s.kubeSecret.Create(ctx, NewTlsSecret(name, certPEM, keyPEM), meta.CreateOptions{DryRun: s.dryRun.dryRunOption()})
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if secret.Type != core.SecretTypeTLS {
+			return fmt.Errorf("%w: secret '%s' is of type '%s' instead of '%s'", ErrInvalidTlsSecret, secret.Name, secret.Type, core.SecretTypeTLS)
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[core.TLSCertKey] = certPEM
+		secret.Data[core.TLSPrivateKeyKey] = keyPEM
+		if s.dryRun == DryRunClient {
+			return nil
+		}
+		_, err = s.kubeSecret.Update(ctx, secret, meta.UpdateOptions{DryRun: s.dryRun.dryRunOption()})
+		return err
+	})
+
+	return created, err
+}
+
+// ValidateTlsSecret checks that the secret exists, is of type kubernetes.io/tls and its certificate covers the hosts.
+// Returns warnings about certificates which are not yet valid, expired or expire soon and an error
+func (s *SecretService) ValidateTlsSecret(ctx context.Context, name string, hosts []string, now time.Time) ([]string, error) {
+	secret, err := s.kubeSecret.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: secret '%s' does not exist", ErrInvalidTlsSecret, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ValidateTlsSecret(secret, hosts, now)
+}
+
+// FindTlsSecret searches the secrets of type kubernetes.io/tls for a valid certificate covering the host.
+// Returns the name and certificate of the secret with the longest remaining validity and ErrTlsSecretNotFound if no secret covers the host
+func (s *SecretService) FindTlsSecret(ctx context.Context, host string, now time.Time) (string, *x509.Certificate, error) {
+	secretList, err := s.kubeSecret.List(ctx, meta.ListOptions{FieldSelector: "type=" + string(core.SecretTypeTLS)})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return SelectTlsSecret(secretList.Items, host, now)
+}
+
+// SelectTlsSecret selects the tls secret with a valid certificate covering the host and the longest remaining validity without accessing the cluster.
+// Secrets with equal validity are selected by name, secrets which are no valid tls secrets are skipped.
+func SelectTlsSecret(secrets []core.Secret, host string, now time.Time) (string, *x509.Certificate, error) {
+	var selected string
+	var selectedCert *x509.Certificate
+	for i := range secrets {
+		cert, err := ParseTlsSecret(&secrets[i])
+		if err != nil || now.Before(cert.NotBefore) || !now.Before(cert.NotAfter) || !CertificateCoversHost(cert, host) {
+			continue
+		}
+		if selectedCert == nil || cert.NotAfter.After(selectedCert.NotAfter) ||
+			(cert.NotAfter.Equal(selectedCert.NotAfter) && secrets[i].Name < selected) {
+			selected, selectedCert = secrets[i].Name, cert
+		}
+	}
+
+	if selectedCert == nil {
+		return "", nil, fmt.Errorf("%w: no valid certificate covers host '%s'", ErrTlsSecretNotFound, host)
+	}
+	return selected, selectedCert, nil
+}
+
+// ValidateTlsSecret checks that the secret is of type kubernetes.io/tls and its certificate covers the hosts without accessing the cluster.
+// Returns warnings about certificates which are not yet valid, expired or expire soon and an error
+func ValidateTlsSecret(secret *core.Secret, hosts []string, now time.Time) ([]string, error) {
+	cert, err := ParseTlsSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, host := range hosts {
+		if !CertificateCoversHost(cert, host) {
+			return nil, fmt.Errorf("%w: certificate of secret '%s' does not cover host '%s' (covered: %s)",
+				ErrCertificateHostMismatch, secret.Name, host, strings.Join(cert.DNSNames, ", "))
+		}
+	}
+
+	return certificateValidityWarnings(secret.Name, cert, now), nil
+}
+
+// ValidateTlsKeyPair checks that the PEM encoded private key matches the certificate and the certificate covers the hosts.
+// Returns warnings about certificates which are not yet valid, expired or expire soon and an error
+func ValidateTlsKeyPair(name string, certPEM []byte, keyPEM []byte, hosts []string, now time.Time) ([]string, error) {
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyPair, err)
+	}
+
+	return ValidateTlsSecret(NewTlsSecret(name, certPEM, keyPEM), hosts, now)
+}
+
+// ParseTlsSecret returns the first certificate of a secret of type kubernetes.io/tls.
+func ParseTlsSecret(secret *core.Secret) (*x509.Certificate, error) {
+	if secret.Type != core.SecretTypeTLS {
+		return nil, fmt.Errorf("%w: secret '%s' is of type '%s' instead of '%s'", ErrInvalidTlsSecret, secret.Name, secret.Type, core.SecretTypeTLS)
+	}
+
+	cert, err := ParseCertificate(secret.Data[core.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret '%s': %s", ErrInvalidTlsSecret, secret.Name, err)
+	}
+	return cert, nil
+}
+
+// ParseCertificate parses the first certificate of a PEM encoded certificate chain.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return nil, errors.New("no PEM encoded certificate found")
+}
+
+// ParseCertificateChain parses all certificates of a PEM encoded certificate chain, the leaf certificate comes first.
+func ParseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return chain, nil
+}
+
+// CertificateCoversHost checks if a DNS name of the certificate equals the host or covers it as wildcard.
+// A wildcard host is only covered by the same wildcard.
+func CertificateCoversHost(cert *x509.Certificate, host string) bool {
+	for _, dnsName := range cert.DNSNames {
+		if HostCoveredBy(host, dnsName) {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateValidityWarnings reports certificates which are not yet valid, expired or expire within CertificateExpiryWarning.
+func certificateValidityWarnings(secretName string, cert *x509.Certificate, now time.Time) []string {
+	var warnings []string
+	if now.Before(cert.NotBefore) {
+		warnings = append(warnings, fmt.Sprintf("certificate of secret '%s' is not valid before %s", secretName, cert.NotBefore.Format(time.RFC3339)))
+	}
+	if remaining := cert.NotAfter.Sub(now); remaining <= 0 {
+		warnings = append(warnings, fmt.Sprintf("certificate of secret '%s' expired at %s", secretName, cert.NotAfter.Format(time.RFC3339)))
+	} else if remaining < CertificateExpiryWarning {
+		warnings = append(warnings, fmt.Sprintf("certificate of secret '%s' expires in %d day(s) at %s", secretName, int(remaining.Hours()/24), cert.NotAfter.Format(time.RFC3339)))
+	}
+	return warnings
+}
+
+var ErrInvalidTlsSecret = errors.New("invalid tls secret")
+var ErrTlsSecretNotFound = errors.New("no matching tls secret found")
+var ErrCertificateHostMismatch = errors.New("certificate does not cover host")
+var ErrInvalidKeyPair = errors.New("invalid certificate and key pair")
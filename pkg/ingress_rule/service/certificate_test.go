@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestValidateTlsSecret(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	validCert := testCertificate(t, []string{"foo.com", "*.example.com"}, now.Add(-24*time.Hour), now.Add(90*24*time.Hour))
+
+	tests := []struct {
+		name             string
+		secret           *core.Secret
+		hosts            []string
+		expectedWarnings []string
+		expectedError    error
+	}{
+		{
+			name:   "certificate covers hosts",
+			secret: testTlsSecret("my-secret", validCert),
+			hosts:  []string{"foo.com", "bar.example.com", "*.example.com"},
+		},
+		{
+			name:          "certificate does not cover host",
+			secret:        testTlsSecret("my-secret", validCert),
+			hosts:         []string{"bar.com"},
+			expectedError: ErrCertificateHostMismatch,
+		},
+		{
+			name:          "wildcard does not cover nested subdomain",
+			secret:        testTlsSecret("my-secret", validCert),
+			hosts:         []string{"foo.bar.example.com"},
+			expectedError: ErrCertificateHostMismatch,
+		},
+		{
+			name: "secret of wrong type",
+			secret: &core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-secret"},
+				Type:       core.SecretTypeOpaque,
+				Data:       map[string][]byte{core.TLSCertKey: validCert},
+			},
+			hosts:         []string{"foo.com"},
+			expectedError: ErrInvalidTlsSecret,
+		},
+		{
+			name:          "secret without certificate",
+			secret:        testTlsSecret("my-secret", []byte("no certificate")),
+			hosts:         []string{"foo.com"},
+			expectedError: ErrInvalidTlsSecret,
+		},
+		{
+			name:             "warn about certificate expiring soon",
+			secret:           testTlsSecret("my-secret", testCertificate(t, []string{"foo.com"}, now.Add(-24*time.Hour), now.Add(10*24*time.Hour))),
+			hosts:            []string{"foo.com"},
+			expectedWarnings: []string{"certificate of secret 'my-secret' expires in 10 day(s) at 2022-03-11T00:00:00Z"},
+		},
+		{
+			name:             "warn about expired certificate",
+			secret:           testTlsSecret("my-secret", testCertificate(t, []string{"foo.com"}, now.Add(-48*time.Hour), now.Add(-24*time.Hour))),
+			hosts:            []string{"foo.com"},
+			expectedWarnings: []string{"certificate of secret 'my-secret' expired at 2022-02-28T00:00:00Z"},
+		},
+		{
+			name:             "warn about certificate which is not yet valid",
+			secret:           testTlsSecret("my-secret", testCertificate(t, []string{"foo.com"}, now.Add(24*time.Hour), now.Add(90*24*time.Hour))),
+			hosts:            []string{"foo.com"},
+			expectedWarnings: []string{"certificate of secret 'my-secret' is not valid before 2022-03-02T00:00:00Z"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings, err := ValidateTlsSecret(test.secret, test.hosts, now)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedWarnings, warnings)
+		})
+	}
+}
+
+func TestValidateTlsKeyPair(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	certPEM, keyPEM, err := GenerateSelfSignedCertificate([]string{"foo.com"}, 90*24*time.Hour, KeyTypeECDSAP256, now)
+	assert.NoError(t, err)
+	_, otherKeyPEM, err := GenerateSelfSignedCertificate([]string{"foo.com"}, 90*24*time.Hour, KeyTypeECDSAP256, now)
+	assert.NoError(t, err)
+
+	warnings, err := ValidateTlsKeyPair("my-secret", certPEM, keyPEM, []string{"foo.com"}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	_, err = ValidateTlsKeyPair("my-secret", certPEM, otherKeyPEM, []string{"foo.com"}, now)
+	assert.ErrorIs(t, err, ErrInvalidKeyPair)
+
+	_, err = ValidateTlsKeyPair("my-secret", certPEM, []byte("no key"), []string{"foo.com"}, now)
+	assert.ErrorIs(t, err, ErrInvalidKeyPair)
+
+	_, err = ValidateTlsKeyPair("my-secret", certPEM, keyPEM, []string{"bar.com"}, now)
+	assert.ErrorIs(t, err, ErrCertificateHostMismatch)
+}
+
+func TestSecretService_ValidateTlsSecret(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset(testTlsSecret("my-secret", testCertificate(t, []string{"foo.com"}, now.Add(-time.Hour), now.Add(90*24*time.Hour))))
+	secretService := SecretService{kubeSecret: clientset.CoreV1().Secrets("default")}
+
+	warnings, err := secretService.ValidateTlsSecret(context.TODO(), "my-secret", []string{"foo.com"}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	_, err = secretService.ValidateTlsSecret(context.TODO(), "my-secrett", []string{"foo.com"}, now)
+	assert.ErrorIs(t, err, ErrInvalidTlsSecret)
+	assert.Contains(t, err.Error(), "secret 'my-secrett' does not exist")
+}
+
+func TestSecretService_CreateOrUpdateTlsSecret(t *testing.T) {
+	opaque := &core.Secret{ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"}, Type: core.SecretTypeOpaque}
+	clientset := fake.NewSimpleClientset(testTlsSecret("existing", []byte("old")), opaque)
+	secretService := SecretService{kubeSecret: clientset.CoreV1().Secrets("default")}
+
+	created, err := secretService.CreateOrUpdateTlsSecret(context.TODO(), "new", []byte("cert"), []byte("key"))
+	assert.NoError(t, err)
+	assert.True(t, created)
+	secret, err := clientset.CoreV1().Secrets("default").Get(context.TODO(), "new", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, core.SecretTypeTLS, secret.Type)
+	assert.Equal(t, []byte("cert"), secret.Data[core.TLSCertKey])
+
+	created, err = secretService.CreateOrUpdateTlsSecret(context.TODO(), "existing", []byte("cert"), []byte("key"))
+	assert.NoError(t, err)
+	assert.False(t, created)
+	secret, err = clientset.CoreV1().Secrets("default").Get(context.TODO(), "existing", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("cert"), secret.Data[core.TLSCertKey])
+	assert.Equal(t, []byte("key"), secret.Data[core.TLSPrivateKeyKey])
+
+	_, err = secretService.CreateOrUpdateTlsSecret(context.TODO(), "opaque", []byte("cert"), []byte("key"))
+	assert.ErrorIs(t, err, ErrInvalidTlsSecret)
+
+	secretService.SetDryRun(DryRunClient, nil)
+	created, err = secretService.CreateOrUpdateTlsSecret(context.TODO(), "dry-run", []byte("cert"), []byte("key"))
+	assert.NoError(t, err)
+	assert.True(t, created)
+	_, err = clientset.CoreV1().Secrets("default").Get(context.TODO(), "dry-run", metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestSelectTlsSecret(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	wildcard := testTlsSecret("wildcard", testCertificate(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(60*24*time.Hour)))
+	wildcardRenewed := testTlsSecret("wildcard-renewed", testCertificate(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(90*24*time.Hour)))
+	wildcardCopy := testTlsSecret("a-wildcard-copy", wildcardRenewed.Data[core.TLSCertKey])
+	expired := testTlsSecret("expired", testCertificate(t, []string{"*.example.com"}, now.Add(-48*time.Hour), now.Add(-24*time.Hour)))
+	notYetValid := testTlsSecret("not-yet-valid", testCertificate(t, []string{"*.example.com"}, now.Add(time.Hour), now.Add(365*24*time.Hour)))
+	foo := testTlsSecret("foo", testCertificate(t, []string{"foo.com"}, now.Add(-time.Hour), now.Add(365*24*time.Hour)))
+	opaque := &core.Secret{ObjectMeta: metav1.ObjectMeta{Name: "opaque"}, Type: core.SecretTypeOpaque}
+
+	tests := []struct {
+		name           string
+		secrets        []*core.Secret
+		host           string
+		expectedSecret string
+		expectedError  error
+	}{
+		{
+			name:           "select secret covering the host",
+			secrets:        []*core.Secret{opaque, foo, wildcard},
+			host:           "bar.example.com",
+			expectedSecret: "wildcard",
+		},
+		{
+			name:           "prefer longest remaining validity",
+			secrets:        []*core.Secret{wildcard, wildcardRenewed, foo},
+			host:           "bar.example.com",
+			expectedSecret: "wildcard-renewed",
+		},
+		{
+			name:           "select by name for equal validity",
+			secrets:        []*core.Secret{wildcardRenewed, wildcardCopy},
+			host:           "bar.example.com",
+			expectedSecret: "a-wildcard-copy",
+		},
+		{
+			name:          "skip expired and not yet valid certificates",
+			secrets:       []*core.Secret{expired, notYetValid, foo},
+			host:          "bar.example.com",
+			expectedError: ErrTlsSecretNotFound,
+		},
+		{
+			name:          "no secret covers the host",
+			secrets:       []*core.Secret{wildcard},
+			host:          "example.com",
+			expectedError: ErrTlsSecretNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var secrets []core.Secret
+			for _, secret := range test.secrets {
+				secrets = append(secrets, *secret)
+			}
+
+			secret, cert, err := SelectTlsSecret(secrets, test.host, now)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedSecret, secret)
+			assert.True(t, CertificateCoversHost(cert, test.host))
+		})
+	}
+}
+
+func TestSecretService_FindTlsSecret(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset(testTlsSecret("wildcard", testCertificate(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(90*24*time.Hour))))
+	secretService := SecretService{kubeSecret: clientset.CoreV1().Secrets("default")}
+
+	secret, _, err := secretService.FindTlsSecret(context.TODO(), "foo.example.com", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "wildcard", secret)
+}
+
+// testCertificate creates a PEM encoded self-signed certificate for the DNS names.
+func testCertificate(t *testing.T, dnsNames []string, notBefore time.Time, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testTlsSecret(name string, cert []byte) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       core.SecretTypeTLS,
+		Data:       map[string][]byte{core.TLSCertKey: cert, core.TLSPrivateKeyKey: []byte("key")},
+	}
+}
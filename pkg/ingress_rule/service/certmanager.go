@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"strings"
+	"time"
+)
+
+const CertManagerIssuerAnnotation = "cert-manager.io/issuer"
+const CertManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+
+// CertificateResource is the cert-manager Certificate, it is read through the dynamic client to avoid a dependency on cert-manager.
+var CertificateResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// CertManagerIssuer selects the issuer cert-manager uses for the tls entries of an ingress.
+type CertManagerIssuer struct {
+	Name    string
+	Cluster bool // the issuer is a ClusterIssuer instead of an Issuer in the namespace of the ingress
+}
+
+// Annotation returns the ingress annotation selecting the issuer.
+func (c CertManagerIssuer) Annotation() string {
+	if c.Cluster {
+		return CertManagerClusterIssuerAnnotation
+	}
+	return CertManagerIssuerAnnotation
+}
+
+// CertManagerSecretName returns the name of the secret cert-manager stores the certificate for the host in.
+// cert-manager names the Certificate resource after the secret.
+func CertManagerSecretName(host string) string {
+	return strings.ToLower(hostSlug(host)) + "-tls"
+}
+
+// ApplyCertManagerIssuer annotates the ingress with the issuer without accessing the cluster.
+// The issuer applies to every tls entry of the ingress, therefore a different issuer already configured is not replaced.
+func ApplyCertManagerIssuer(ingress *networking.Ingress, issuer CertManagerIssuer) error {
+	for _, annotation := range []string{CertManagerIssuerAnnotation, CertManagerClusterIssuerAnnotation} {
+		value, ok := ingress.Annotations[annotation]
+		if ok && (annotation != issuer.Annotation() || value != issuer.Name) {
+			return fmt.Errorf("%w: ingress '%s' is annotated with %s=%s", ErrCertManagerIssuerConflict, ingress.Name, annotation, value)
+		}
+	}
+
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[issuer.Annotation()] = issuer.Name
+	return nil
+}
+
+// removeCertManagerIssuerWithoutTls removes the issuer annotations once the ingress has no tls entries left.
+func removeCertManagerIssuerWithoutTls(ingress *networking.Ingress) {
+	if len(ingress.Spec.TLS) != 0 {
+		return
+	}
+	delete(ingress.Annotations, CertManagerIssuerAnnotation)
+	delete(ingress.Annotations, CertManagerClusterIssuerAnnotation)
+}
+
+// CertificateService reads cert-manager Certificate resources.
+type CertificateService struct {
+	kubeCertificate dynamic.ResourceInterface
+}
+
+func NewCertificateService(client dynamic.Interface, namespace string) *CertificateService {
+	return &CertificateService{
+		kubeCertificate: client.Resource(CertificateResource).Namespace(namespace),
+	}
+}
+
+// WaitForCertificate polls the Certificate until it is ready or the context is done.
+// A missing Certificate is awaited as well since cert-manager creates it after the ingress has been updated.
+// Returns ErrCertificateNotReady with the last reported reason if the context is done first
+func (c *CertificateService) WaitForCertificate(ctx context.Context, name string, interval time.Duration) error {
+	reason := "certificate does not exist yet"
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		certificate, err := c.kubeCertificate.Get(ctx, name, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		var ready bool
+		ready, reason = CertificateReady(certificate)
+		return ready, nil
+	}, ctx.Done())
+
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("%w: certificate '%s': %s", ErrCertificateNotReady, name, reason)
+	}
+	return err
+}
+
+// CertificateReady evaluates the Ready condition of a Certificate.
+// Returns if the certificate is ready and the message of the condition
+func CertificateReady(certificate *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		return condition["status"] == "True", message
+	}
+	return false, "certificate has no Ready condition yet"
+}
+
+var ErrCertManagerIssuerConflict = errors.New("ingress already uses another cert-manager issuer")
+var ErrCertificateNotReady = errors.New("certificate is not ready")
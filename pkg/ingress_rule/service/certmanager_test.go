@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"testing"
+	"time"
+)
+
+func TestApplyCertManagerIssuer(t *testing.T) {
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		issuer              CertManagerIssuer
+		expectedAnnotations map[string]string
+		expectedError       error
+	}{
+		{
+			name:                "annotate issuer",
+			issuer:              CertManagerIssuer{Name: "letsencrypt"},
+			expectedAnnotations: map[string]string{CertManagerIssuerAnnotation: "letsencrypt"},
+		},
+		{
+			name:                "annotate cluster issuer and keep other annotations",
+			annotations:         map[string]string{"foo": "bar"},
+			issuer:              CertManagerIssuer{Name: "letsencrypt", Cluster: true},
+			expectedAnnotations: map[string]string{"foo": "bar", CertManagerClusterIssuerAnnotation: "letsencrypt"},
+		},
+		{
+			name:                "same issuer already annotated",
+			annotations:         map[string]string{CertManagerClusterIssuerAnnotation: "letsencrypt"},
+			issuer:              CertManagerIssuer{Name: "letsencrypt", Cluster: true},
+			expectedAnnotations: map[string]string{CertManagerClusterIssuerAnnotation: "letsencrypt"},
+		},
+		{
+			name:          "other issuer already annotated",
+			annotations:   map[string]string{CertManagerIssuerAnnotation: "staging"},
+			issuer:        CertManagerIssuer{Name: "letsencrypt"},
+			expectedError: ErrCertManagerIssuerConflict,
+		},
+		{
+			name:          "issuer of other kind already annotated",
+			annotations:   map[string]string{CertManagerIssuerAnnotation: "letsencrypt"},
+			issuer:        CertManagerIssuer{Name: "letsencrypt", Cluster: true},
+			expectedError: ErrCertManagerIssuerConflict,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := NewIngress("my-ingress", "")
+			ingress.Annotations = test.annotations
+
+			err := ApplyCertManagerIssuer(ingress, test.issuer)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedAnnotations, ingress.Annotations)
+		})
+	}
+}
+
+func TestApplyAddRuleWithIssuer_existingRule(t *testing.T) {
+	issuer := CertManagerIssuer{Name: "letsencrypt"}
+	secret := CertManagerSecretName("foo.com")
+	ingress := NewIngress("my-ingress", "")
+	rule := CreateIngressRule("foo.com", "/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Number: 80})
+	assert.NoError(t, ApplyAddRule(ingress, rule, "", false))
+
+	// tls entry and issuer are added to the identical existing rule
+	assert.NoError(t, ApplyAddRuleWithIssuer(ingress, rule, secret, false, issuer))
+	assert.Equal(t, []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "foo-com-tls"}}, ingress.Spec.TLS)
+	assert.Equal(t, "letsencrypt", ingress.Annotations[CertManagerIssuerAnnotation])
+	assert.Equal(t, ErrIngressRuleAlreadyExists, ApplyAddRuleWithIssuer(ingress, rule, secret, false, issuer))
+
+	// the issuer is added when only the tls entry exists
+	delete(ingress.Annotations, CertManagerIssuerAnnotation)
+	assert.NoError(t, ApplyAddRuleWithIssuer(ingress, rule, secret, false, issuer))
+	assert.Equal(t, "letsencrypt", ingress.Annotations[CertManagerIssuerAnnotation])
+}
+
+func TestApplyDeleteRule_removesCertManagerIssuer(t *testing.T) {
+	ingress := NewIngress("my-ingress", "")
+	issuer := CertManagerIssuer{Name: "letsencrypt"}
+	for _, host := range []string{"foo.com", "bar.com"} {
+		rule := CreateIngressRule(host, "/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Number: 80})
+		assert.NoError(t, ApplyAddRule(ingress, rule, CertManagerSecretName(host), false))
+	}
+	assert.NoError(t, ApplyCertManagerIssuer(ingress, issuer))
+
+	host := "foo.com"
+	_, _, err := ApplyDeleteRule(ingress, RuleSelector{Host: &host})
+	assert.NoError(t, err)
+	assert.Equal(t, []networking.IngressTLS{{Hosts: []string{"bar.com"}, SecretName: "bar-com-tls"}}, ingress.Spec.TLS)
+	assert.Equal(t, "letsencrypt", ingress.Annotations[CertManagerIssuerAnnotation])
+
+	ingress.Spec.DefaultBackend = &networking.IngressBackend{Service: &networking.IngressServiceBackend{Name: "default"}}
+	host = "bar.com"
+	_, _, err = ApplyDeleteRule(ingress, RuleSelector{Host: &host})
+	assert.NoError(t, err)
+	assert.Empty(t, ingress.Spec.TLS)
+	assert.NotContains(t, ingress.Annotations, CertManagerIssuerAnnotation)
+}
+
+func TestCertificateService_WaitForCertificate(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		testCertificateResource("ready", "True", "Certificate is up to date and has not expired"),
+		testCertificateResource("pending", "False", "Issuing certificate as Secret does not exist"))
+	certificateService := NewCertificateService(client, "default")
+
+	assert.NoError(t, certificateService.WaitForCertificate(context.TODO(), "ready", time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	err := certificateService.WaitForCertificate(ctx, "pending", time.Millisecond)
+	assert.ErrorIs(t, err, ErrCertificateNotReady)
+	assert.Contains(t, err.Error(), "Issuing certificate as Secret does not exist")
+
+	ctx, cancel = context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	err = certificateService.WaitForCertificate(ctx, "missing", time.Millisecond)
+	assert.ErrorIs(t, err, ErrCertificateNotReady)
+}
+
+func TestCertManagerSecretName(t *testing.T) {
+	assert.Equal(t, "foo-example-com-tls", CertManagerSecretName("foo.example.com"))
+	assert.Equal(t, "wildcard-example-com-tls", CertManagerSecretName("*.example.com"))
+}
+
+func testCertificateResource(name string, status string, message string) *unstructured.Unstructured {
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": status, "message": message},
+			},
+		},
+	}}
+	certificate.SetGroupVersionKind(CertificateResource.GroupVersion().WithKind("Certificate"))
+	certificate.SetName(name)
+	certificate.SetNamespace("default")
+	return certificate
+}
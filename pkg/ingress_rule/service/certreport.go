@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"strings"
+	"time"
+)
+
+type CertificateStatus string
+
+const (
+	CertificateOK             CertificateStatus = "OK"
+	CertificateExpiringSoon   CertificateStatus = "ExpiringSoon"
+	CertificateExpired        CertificateStatus = "Expired"
+	CertificateNotYetValid    CertificateStatus = "NotYetValid"
+	CertificateHostNotCovered CertificateStatus = "HostNotCovered"
+	CertificateSecretMissing  CertificateStatus = "SecretMissing"
+	CertificateSecretInvalid  CertificateStatus = "SecretInvalid"
+	CertificateNoTls          CertificateStatus = "NoTls" // host of a rule without tls configuration
+)
+
+// CertificateReportRow is the certificate of a host of a tls entry or a host of a rule without tls configuration.
+type CertificateReportRow struct {
+	Namespace     string            `json:"namespace"`
+	Ingress       string            `json:"ingress"`
+	Host          string            `json:"host"` // empty for tls entries without hosts
+	Secret        string            `json:"secret,omitempty"`
+	Issuer        string            `json:"issuer,omitempty"`
+	NotAfter      *time.Time        `json:"notAfter,omitempty"` // earliest expiry of the certificate chain
+	DaysRemaining *int              `json:"daysRemaining,omitempty"`
+	Covered       bool              `json:"covered"` // the leaf certificate covers the host
+	Status        CertificateStatus `json:"status"`
+	Message       string            `json:"message,omitempty"`
+}
+
+// CertificateReport reports the certificate of every host of the tls entries and every host of a rule without tls configuration without accessing the cluster.
+// The secrets map contains the referenced secrets of the namespace of the ingress by name, missing secrets are reported as CertificateSecretMissing.
+// Secrets which could not be read are reported as CertificateSecretMissing with the error of the secretErrors map.
+func CertificateReport(ingress *networking.Ingress, secrets map[string]*core.Secret, secretErrors map[string]error, now time.Time) []CertificateReportRow {
+	var rows []CertificateReportRow
+
+	var tlsHosts []string
+	for _, tlsEntry := range ingress.Spec.TLS {
+		hosts := tlsEntry.Hosts
+		if len(hosts) == 0 {
+			// entry without hosts, i.e. the default certificate
+			hosts = []string{""}
+		}
+		for _, host := range hosts {
+			rows = append(rows, certificateReportRow(ingress, host, tlsEntry.SecretName, secrets[tlsEntry.SecretName], secretErrors[tlsEntry.SecretName], now))
+		}
+		tlsHosts = append(tlsHosts, tlsEntry.Hosts...)
+	}
+
+	reported := map[string]bool{}
+	for _, host := range ruleHosts(ingress) {
+		if reported[host] || hostCoveredByAny(host, tlsHosts) {
+			continue
+		}
+		reported[host] = true
+		rows = append(rows, CertificateReportRow{
+			Namespace: ingress.Namespace,
+			Ingress:   ingress.Name,
+			Host:      host,
+			Status:    CertificateNoTls,
+			Message:   "host of a rule has no tls configuration",
+		})
+	}
+
+	return rows
+}
+
+func certificateReportRow(ingress *networking.Ingress, host string, secretName string, secret *core.Secret, secretErr error, now time.Time) CertificateReportRow {
+	row := CertificateReportRow{
+		Namespace: ingress.Namespace,
+		Ingress:   ingress.Name,
+		Host:      host,
+		Secret:    secretName,
+	}
+	if secret == nil && secretErr != nil {
+		row.Status = CertificateSecretMissing
+		row.Message = fmt.Sprintf("failed to read secret '%s': %s", secretName, secretErr)
+		return row
+	}
+	if secret == nil {
+		row.Status = CertificateSecretMissing
+		row.Message = fmt.Sprintf("secret '%s' does not exist", secretName)
+		return row
+	}
+	if secret.Type != core.SecretTypeTLS {
+		row.Status = CertificateSecretInvalid
+		row.Message = fmt.Sprintf("secret '%s' is of type '%s' instead of '%s'", secretName, secret.Type, core.SecretTypeTLS)
+		return row
+	}
+	chain, err := ParseCertificateChain(secret.Data[core.TLSCertKey])
+	if err != nil {
+		row.Status = CertificateSecretInvalid
+		row.Message = err.Error()
+		return row
+	}
+
+	leaf := chain[0]
+	notAfter, notBefore := leaf.NotAfter, leaf.NotBefore
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+		if cert.NotBefore.After(notBefore) {
+			notBefore = cert.NotBefore
+		}
+	}
+	daysRemaining := int(notAfter.Sub(now).Hours() / 24)
+	row.Issuer = leaf.Issuer.CommonName
+	if row.Issuer == "" {
+		row.Issuer = leaf.Issuer.String()
+	}
+	row.NotAfter = &notAfter
+	row.DaysRemaining = &daysRemaining
+	row.Covered = host == "" || CertificateCoversHost(leaf, host)
+
+	switch {
+	case !row.Covered:
+		row.Status = CertificateHostNotCovered
+		row.Message = fmt.Sprintf("certificate covers %s", strings.Join(leaf.DNSNames, ", "))
+	case !now.Before(notAfter):
+		row.Status = CertificateExpired
+	case now.Before(notBefore):
+		row.Status = CertificateNotYetValid
+	case notAfter.Sub(now) < CertificateExpiryWarning:
+		row.Status = CertificateExpiringSoon
+	default:
+		row.Status = CertificateOK
+	}
+	return row
+}
+
+// hostCoveredByAny checks if the host equals one of the patterns or is matched by a wildcard pattern.
+func hostCoveredByAny(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if HostCoveredBy(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestCertificateReport(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	wildcard := testTlsSecret("wildcard", testCertificate(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(60*24*time.Hour)))
+	expiring := testTlsSecret("expiring", testCertificate(t, []string{"foo.com"}, now.Add(-time.Hour), now.Add(10*24*time.Hour)))
+	chain := testTlsSecret("chain", append(
+		testCertificate(t, []string{"bar.com"}, now.Add(-time.Hour), now.Add(90*24*time.Hour)),
+		testCertificate(t, []string{"intermediate"}, now.Add(-time.Hour), now.Add(20*24*time.Hour))...))
+	opaque := &core.Secret{ObjectMeta: metav1.ObjectMeta{Name: "opaque"}, Type: core.SecretTypeOpaque}
+
+	ingress := NewIngress("my-ingress", "")
+	ingress.Namespace = "default"
+	for _, host := range []string{"a.example.com", "foo.com", "bar.com", "baz.com", "qux.com", "plain.com"} {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, *CreateIngressRule(host, "/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Number: 80}))
+	}
+	ingress.Spec.TLS = []networking.IngressTLS{
+		{Hosts: []string{"*.example.com"}, SecretName: "wildcard"},
+		{Hosts: []string{"foo.com", "qux.com"}, SecretName: "expiring"},
+		{Hosts: []string{"bar.com"}, SecretName: "chain"},
+		{Hosts: []string{"baz.com"}, SecretName: "missing"},
+		{SecretName: "opaque"},
+	}
+	secrets := map[string]*core.Secret{"wildcard": wildcard, "expiring": expiring, "chain": chain, "opaque": opaque}
+
+	rows := CertificateReport(ingress, secrets, nil, now)
+
+	var hosts []string
+	var statuses []CertificateStatus
+	for _, row := range rows {
+		hosts = append(hosts, row.Host)
+		statuses = append(statuses, row.Status)
+	}
+	assert.Equal(t, []string{"*.example.com", "foo.com", "qux.com", "bar.com", "baz.com", "", "plain.com"}, hosts)
+	assert.Equal(t, []CertificateStatus{
+		CertificateOK, CertificateExpiringSoon, CertificateHostNotCovered, CertificateExpiringSoon,
+		CertificateSecretMissing, CertificateSecretInvalid, CertificateNoTls,
+	}, statuses)
+
+	assert.Equal(t, "*.example.com", rows[0].Issuer)
+	assert.Equal(t, 60, *rows[0].DaysRemaining)
+	assert.True(t, rows[0].Covered)
+	assert.False(t, rows[2].Covered)
+	// the intermediate certificate expires first
+	assert.Equal(t, now.Add(20*24*time.Hour), *rows[3].NotAfter)
+	assert.Nil(t, rows[4].NotAfter)
+}
+
+func TestCertificateReport_expired(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	expired := testTlsSecret("expired", testCertificate(t, []string{"foo.com"}, now.Add(-48*time.Hour), now.Add(-24*time.Hour)))
+
+	ingress := NewIngress("my-ingress", "")
+	ingress.Spec.Rules = []networking.IngressRule{*CreateIngressRule("foo.com", "/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Number: 80})}
+	ingress.Spec.TLS = []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "expired"}}
+
+	rows := CertificateReport(ingress, map[string]*core.Secret{"expired": expired}, nil, now)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, CertificateExpired, rows[0].Status)
+	assert.Equal(t, -1, *rows[0].DaysRemaining)
+}
+
+func TestCertificateReport_unreadableSecret(t *testing.T) {
+	ingress := NewIngress("my-ingress", "")
+	ingress.Spec.Rules = []networking.IngressRule{*CreateIngressRule("foo.com", "/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Number: 80})}
+	ingress.Spec.TLS = []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "forbidden"}}
+
+	rows := CertificateReport(ingress, nil, map[string]error{"forbidden": errors.New("access denied")}, time.Now())
+	assert.Len(t, rows, 1)
+	assert.Equal(t, CertificateSecretMissing, rows[0].Status)
+	assert.Equal(t, "failed to read secret 'forbidden': access denied", rows[0].Message)
+}
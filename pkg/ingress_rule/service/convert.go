@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	networking "k8s.io/api/networking/v1"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sort"
+	"strings"
+)
+
+// ignoredAnnotations are not specific to an ingress controller and therefore not reported by the conversion.
+var ignoredAnnotations = map[string]bool{
+	"kubectl.kubernetes.io/last-applied-configuration": true,
+	ManagedRulesAnnotation:                             true,
+}
+
+// Conversion is the result of converting an ingress to Gateway API resources.
+type Conversion struct {
+	// Routes contains a HTTPRoute per host of the ingress.
+	Routes []*gateway.HTTPRoute
+	// Listeners are the gateway listeners terminating tls for the hosts of the ingress.
+	Listeners []gateway.Listener
+	// Unsupported describes the constructs of the ingress which could not be converted.
+	Unsupported []string
+}
+
+// ConvertIngress translates the rules, tls configuration and default backend of the ingress to HTTPRoutes attached to the parent refs.
+// The hostnames of a HTTPRoute apply to all its rules, therefore a HTTPRoute is created per host.
+// Constructs which can not be expressed by HTTPRoutes are skipped and reported.
+func ConvertIngress(ingress *networking.Ingress, parentRefs []gateway.ParentRef) *Conversion {
+	conversion := &Conversion{}
+	routes := map[string]*gateway.HTTPRoute{}
+	var hosts []string
+
+	routeForHost := func(host string) *gateway.HTTPRoute {
+		if route, ok := routes[host]; ok {
+			return route
+		}
+		route := NewHTTPRoute("", parentRefs)
+		route.Namespace = ingress.Namespace
+		if host != "" {
+			route.Spec.Hostnames = []gateway.Hostname{gateway.Hostname(host)}
+		}
+		routes[host] = route
+		hosts = append(hosts, host)
+		return route
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			conversion.unsupported("rule for host '%s' without http paths", rule.Host)
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			routeRule, err := convertIngressPath(path)
+			if err != nil {
+				conversion.unsupported("path '%s' of host '%s': %v", path.Path, rule.Host, err)
+				continue
+			}
+			route := routeForHost(rule.Host)
+			route.Spec.Rules = append(route.Spec.Rules, *routeRule)
+		}
+	}
+
+	if backend := ingress.Spec.DefaultBackend; backend != nil {
+		backendRef, err := convertIngressBackend(*backend)
+		if err != nil {
+			conversion.unsupported("default backend: %v", err)
+		} else {
+			// the default backend receives all requests which are not matched by a more specific route
+			route := routeForHost("")
+			if findRouteMatch(route, "/", networking.PathTypePrefix) {
+				conversion.unsupported("default backend is shadowed by the rule for path '/' without host")
+			} else {
+				route.Spec.Rules = append(route.Spec.Rules, gateway.HTTPRouteRule{BackendRefs: []gateway.HTTPBackendRef{*backendRef}})
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		route := routes[host]
+		route.Name = routeName(ingress.Name, host, len(hosts))
+		conversion.Routes = append(conversion.Routes, route)
+	}
+
+	conversion.Listeners = convertIngressTls(ingress)
+
+	if ingress.Spec.IngressClassName != nil {
+		conversion.unsupported("ingress class '%s', attach the routes to a gateway of the corresponding gateway class", *ingress.Spec.IngressClassName)
+	}
+
+	var annotations []string
+	for annotation := range ingress.Annotations {
+		if !ignoredAnnotations[annotation] {
+			annotations = append(annotations, annotation)
+		}
+	}
+	sort.Strings(annotations)
+	for _, annotation := range annotations {
+		conversion.unsupported("annotation '%s'", annotation)
+	}
+
+	return conversion
+}
+
+func (c *Conversion) unsupported(format string, args ...interface{}) {
+	c.Unsupported = append(c.Unsupported, fmt.Sprintf(format, args...))
+}
+
+// convertIngressPath translates a path of an ingress rule to a HTTPRoute rule.
+func convertIngressPath(path networking.HTTPIngressPath) (*gateway.HTTPRouteRule, error) {
+	pathType := networking.PathTypeImplementationSpecific
+	if path.PathType != nil {
+		pathType = *path.PathType
+	}
+	matchType, err := PathMatchType(pathType)
+	if err != nil {
+		return nil, err
+	}
+
+	backendRef, err := convertIngressBackend(path.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	value := path.Path
+	if value == "" {
+		value = "/"
+	}
+
+	return &gateway.HTTPRouteRule{
+		Matches: []gateway.HTTPRouteMatch{
+			{
+				Path: &gateway.HTTPPathMatch{
+					Type:  &matchType,
+					Value: &value,
+				},
+			},
+		},
+		BackendRefs: []gateway.HTTPBackendRef{*backendRef},
+	}, nil
+}
+
+// convertIngressBackend translates an ingress backend to a backend ref, services have to reference their port by number.
+func convertIngressBackend(backend networking.IngressBackend) (*gateway.HTTPBackendRef, error) {
+	ref := gateway.BackendObjectReference{}
+	switch {
+	case backend.Service != nil:
+		if backend.Service.Port.Name != "" {
+			return nil, fmt.Errorf("named port '%s' of service '%s' is not supported by HTTPRoute", backend.Service.Port.Name, backend.Service.Name)
+		}
+		port := gateway.PortNumber(backend.Service.Port.Number)
+		ref.Name = gateway.ObjectName(backend.Service.Name)
+		ref.Port = &port
+	case backend.Resource != nil:
+		group := gateway.Group("")
+		if backend.Resource.APIGroup != nil {
+			group = gateway.Group(*backend.Resource.APIGroup)
+		}
+		kind := gateway.Kind(backend.Resource.Kind)
+		ref.Group = &group
+		ref.Kind = &kind
+		ref.Name = gateway.ObjectName(backend.Resource.Name)
+	default:
+		return nil, fmt.Errorf("backend without service or resource")
+	}
+
+	return &gateway.HTTPBackendRef{BackendRef: gateway.BackendRef{BackendObjectReference: ref}}, nil
+}
+
+// convertIngressTls returns a HTTPS listener per host of the tls configuration referencing its secret.
+func convertIngressTls(ingress *networking.Ingress) []gateway.Listener {
+	var listeners []gateway.Listener
+	mode := gateway.TLSModeTerminate
+
+	for _, tls := range ingress.Spec.TLS {
+		hosts := tls.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		for _, host := range hosts {
+			group := gateway.Group("")
+			kind := gateway.Kind("Secret")
+			namespace := gateway.Namespace(ingress.Namespace)
+			listener := gateway.Listener{
+				Name:     gateway.SectionName(hostSlug(host) + "-https"),
+				Port:     443,
+				Protocol: gateway.HTTPSProtocolType,
+				TLS: &gateway.GatewayTLSConfig{
+					Mode: &mode,
+					CertificateRefs: []*gateway.SecretObjectReference{
+						{
+							Group: &group,
+							Kind:  &kind,
+							Name:  gateway.ObjectName(tls.SecretName),
+						},
+					},
+				},
+			}
+			if ingress.Namespace != "" {
+				listener.TLS.CertificateRefs[0].Namespace = &namespace
+			}
+			if host != "" {
+				hostname := gateway.Hostname(host)
+				listener.Hostname = &hostname
+			}
+			listeners = append(listeners, listener)
+		}
+	}
+
+	return listeners
+}
+
+// findRouteMatch checks if a rule of the HTTPRoute matches the path with the path type.
+func findRouteMatch(route *gateway.HTTPRoute, path string, pathType networking.PathType) bool {
+	for _, rule := range route.Spec.Rules {
+		for _, match := range effectiveMatches(rule) {
+			matchPath, matchPathType := ingressPath(match)
+			if matchPath == path && matchPathType == pathType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// routeName returns the name of the HTTPRoute for the host, a single HTTPRoute keeps the name of the ingress.
+func routeName(ingressName string, host string, routes int) string {
+	if routes == 1 {
+		return ingressName
+	}
+	return ingressName + "-" + hostSlug(host)
+}
+
+// hostSlug turns a host into a valid name, the rule without host is named "default".
+func hostSlug(host string) string {
+	if host == "" {
+		return "default"
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(host, "*", "wildcard"), ".", "-")
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"testing"
+)
+
+func TestConvertIngress(t *testing.T) {
+	ingressClass := "nginx"
+	ingress := &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target":       "/",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		Spec: networking.IngressSpec{
+			IngressClassName: &ingressClass,
+			DefaultBackend: &networking.IngressBackend{
+				Service: &networking.IngressServiceBackend{Name: "fallback", Port: networking.ServiceBackendPort{Number: 80}},
+			},
+			Rules: []networking.IngressRule{
+				ruleHostFooTwoRules(),
+				{
+					Host: "bar.com",
+					IngressRuleValue: networking.IngressRuleValue{HTTP: &networking.HTTPIngressRuleValue{Paths: []networking.HTTPIngressPath{
+						{
+							Path:     "/regex",
+							PathType: pathTypePtr(networking.PathTypeImplementationSpecific),
+							Backend:  networking.IngressBackend{Service: &networking.IngressServiceBackend{Name: "bar", Port: networking.ServiceBackendPort{Number: 80}}},
+						},
+						{
+							Path:     "/named",
+							PathType: pathTypePtr(networking.PathTypePrefix),
+							Backend:  networking.IngressBackend{Service: &networking.IngressServiceBackend{Name: "bar", Port: networking.ServiceBackendPort{Name: "http"}}},
+						},
+						{
+							Path:     "/",
+							PathType: pathTypePtr(networking.PathTypeExact),
+							Backend:  networking.IngressBackend{Service: &networking.IngressServiceBackend{Name: "bar", Port: networking.ServiceBackendPort{Number: 8080}}},
+						},
+					}}},
+				},
+			},
+			TLS: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "foo-tls"}},
+		},
+	}
+	parentRefs := []gateway.ParentRef{NewGatewayParentRef("gw", "infra", "")}
+
+	conversion := ConvertIngress(ingress, parentRefs)
+
+	if assert.Len(t, conversion.Routes, 3) {
+		foo := conversion.Routes[0]
+		assert.Equal(t, "foo-foo-com", foo.Name)
+		assert.Equal(t, "default", foo.Namespace)
+		assert.Equal(t, []gateway.Hostname{"foo.com"}, foo.Spec.Hostnames)
+		assert.Equal(t, parentRefs, foo.Spec.ParentRefs)
+		assert.Equal(t, []gateway.HTTPRouteRule{
+			*routeRule("/", networking.PathTypePrefix, "service-foo", 80),
+			*routeRule("/2", networking.PathTypePrefix, "service-foo-2", 80),
+		}, foo.Spec.Rules)
+
+		bar := conversion.Routes[1]
+		assert.Equal(t, "foo-bar-com", bar.Name)
+		assert.Equal(t, []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypeExact, "bar", 8080)}, bar.Spec.Rules)
+
+		defaultRoute := conversion.Routes[2]
+		assert.Equal(t, "foo-default", defaultRoute.Name)
+		assert.Nil(t, defaultRoute.Spec.Hostnames)
+		if assert.Len(t, defaultRoute.Spec.Rules, 1) {
+			assert.Nil(t, defaultRoute.Spec.Rules[0].Matches)
+			assert.Equal(t, gateway.ObjectName("fallback"), defaultRoute.Spec.Rules[0].BackendRefs[0].Name)
+		}
+	}
+
+	if assert.Len(t, conversion.Listeners, 1) {
+		listener := conversion.Listeners[0]
+		assert.Equal(t, gateway.SectionName("foo-com-https"), listener.Name)
+		assert.Equal(t, gateway.Hostname("foo.com"), *listener.Hostname)
+		assert.Equal(t, gateway.HTTPSProtocolType, listener.Protocol)
+		assert.Equal(t, gateway.ObjectName("foo-tls"), listener.TLS.CertificateRefs[0].Name)
+		assert.Equal(t, gateway.Namespace("default"), *listener.TLS.CertificateRefs[0].Namespace)
+	}
+
+	assert.Equal(t, []string{
+		"path '/regex' of host 'bar.com': path type 'ImplementationSpecific' is not supported by HTTPRoute",
+		"path '/named' of host 'bar.com': named port 'http' of service 'bar' is not supported by HTTPRoute",
+		"ingress class 'nginx', attach the routes to a gateway of the corresponding gateway class",
+		"annotation 'nginx.ingress.kubernetes.io/rewrite-target'",
+	}, conversion.Unsupported)
+}
+
+func TestConvertIngress_SingleHostKeepsName(t *testing.T) {
+	ingress := &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{ruleHostFoo()},
+		},
+	}
+
+	conversion := ConvertIngress(ingress, nil)
+
+	if assert.Len(t, conversion.Routes, 1) {
+		assert.Equal(t, "foo", conversion.Routes[0].Name)
+	}
+	assert.Empty(t, conversion.Listeners)
+	assert.Empty(t, conversion.Unsupported)
+}
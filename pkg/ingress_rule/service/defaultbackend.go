@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreateServiceBackend creates a backend referencing the service either by port number or by port name.
+func CreateServiceBackend(serviceName string, port networking.ServiceBackendPort) networking.IngressBackend {
+	return networking.IngressBackend{
+		Service: &networking.IngressServiceBackend{
+			Name: serviceName,
+			Port: port,
+		},
+	}
+}
+
+// CreateResourceBackend creates a backend referencing a resource, an empty api group references the core api group.
+func CreateResourceBackend(apiGroup string, kind string, name string) networking.IngressBackend {
+	resource := &core.TypedLocalObjectReference{
+		Kind: kind,
+		Name: name,
+	}
+	if apiGroup != "" {
+		resource.APIGroup = &apiGroup
+	}
+	return networking.IngressBackend{Resource: resource}
+}
+
+// SetDefaultBackend sets the default backend of the ingress. If the ingress does not exist a new ingress without rules will be created.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns the replaced default backend, if the resource has been created and an error
+func (i *IngressService) SetDefaultBackend(ctx context.Context, backend networking.IngressBackend) (replaced *networking.IngressBackend, created bool, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		replaced, created = nil, false
+
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			ingress = NewIngress(i.ingressName, i.ingressClassName)
+			if _, err = ApplySetDefaultBackend(ingress, backend); err != nil {
+				return err
+			}
+			created = true
+			return i.create(ctx, ingress)
+		} else if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		if replaced, err = ApplySetDefaultBackend(ingress, backend); err != nil {
+			return err
+		}
+		return i.update(ctx, live, ingress)
+	})
+
+	return replaced, created, err
+}
+
+// ApplySetDefaultBackend sets the default backend of the ingress without accessing the cluster.
+// Returns the replaced default backend and ErrDefaultBackendAlreadyExists if the ingress already uses the backend.
+func ApplySetDefaultBackend(ingress *networking.Ingress, backend networking.IngressBackend) (*networking.IngressBackend, error) {
+	replaced := ingress.Spec.DefaultBackend
+	if replaced != nil && equality.Semantic.DeepEqual(*replaced, backend) {
+		return nil, ErrDefaultBackendAlreadyExists
+	}
+
+	ingress.Spec.DefaultBackend = backend.DeepCopy()
+	return replaced, nil
+}
+
+// UnsetDefaultBackend removes the default backend of the ingress. Deletes the ingress if there are no rules left.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns if the resource has been deleted and an error
+func (i *IngressService) UnsetDefaultBackend(ctx context.Context) (deleted bool, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		deleted = false
+
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		empty, err := ApplyUnsetDefaultBackend(ingress)
+		if err != nil {
+			return err
+		}
+		if empty {
+			// delete ingress when there are neither rules nor a default backend left
+			deleted = true
+			return i.delete(ctx, live)
+		}
+
+		return i.update(ctx, live, ingress)
+	})
+
+	return deleted, err
+}
+
+// ApplyUnsetDefaultBackend removes the default backend of the ingress without accessing the cluster.
+// Returns if there are no rules left and an error
+func ApplyUnsetDefaultBackend(ingress *networking.Ingress) (empty bool, err error) {
+	if ingress.Spec.DefaultBackend == nil {
+		return false, ErrDefaultBackendNotFound
+	}
+
+	ingress.Spec.DefaultBackend = nil
+	return len(ingress.Spec.Rules) == 0, nil
+}
+
+var ErrDefaultBackendAlreadyExists = errors.New("default backend already exists")
+var ErrDefaultBackendNotFound = errors.New("ingress has no default backend")
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/typed/networking/v1/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestIngressService_SetDefaultBackend(t *testing.T) {
+	serviceBackend := CreateServiceBackend("service-foo", networking.ServiceBackendPort{Number: 80})
+	resourceBackend := CreateResourceBackend("k8s.example.com", "StorageBucket", "static-assets")
+
+	tests := []struct {
+		name             string
+		existingIngress  *networking.Ingress
+		backend          networking.IngressBackend
+		expectedReplaced *networking.IngressBackend
+		expectedCreated  bool
+		expectedError    error
+	}{
+		{
+			name:            "set default backend of new ingress",
+			backend:         serviceBackend,
+			expectedCreated: true,
+		},
+		{
+			name:            "set default backend of existing ingress",
+			existingIngress: ingressWithDefaultBackend(nil, ruleHostFoo()),
+			backend:         resourceBackend,
+		},
+		{
+			name:             "replace default backend",
+			existingIngress:  ingressWithDefaultBackend(&serviceBackend),
+			backend:          resourceBackend,
+			expectedReplaced: &serviceBackend,
+		},
+		{
+			name:            "same default backend returns ErrDefaultBackendAlreadyExists",
+			existingIngress: ingressWithDefaultBackend(&serviceBackend),
+			backend:         serviceBackend,
+			expectedError:   ErrDefaultBackendAlreadyExists,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ingress *networking.Ingress
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				if test.existingIngress == nil {
+					return true, nil, errors2.NewNotFound(action.GetResource().GroupResource(), action.(clienttesting.GetAction).GetName())
+				}
+				return true, test.existingIngress.DeepCopy(), nil
+			})
+			f.AddReactor("*", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				ingress = action.(clienttesting.CreateAction).GetObject().(*networking.Ingress)
+				return true, ingress, nil
+			})
+
+			ingressService := IngressService{
+				kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+				ingressName: "foo",
+			}
+
+			replaced, created, err := ingressService.SetDefaultBackend(context.TODO(), test.backend)
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError != nil {
+				assert.Nil(t, ingress)
+				return
+			}
+			assert.Equal(t, test.expectedReplaced, replaced)
+			assert.Equal(t, test.expectedCreated, created)
+			assert.Equal(t, &test.backend, ingress.Spec.DefaultBackend)
+			if test.existingIngress != nil {
+				assert.Equal(t, test.existingIngress.Spec.Rules, ingress.Spec.Rules)
+			}
+		})
+	}
+}
+
+func TestIngressService_UnsetDefaultBackend(t *testing.T) {
+	serviceBackend := CreateServiceBackend("service-foo", networking.ServiceBackendPort{Number: 80})
+
+	tests := []struct {
+		name            string
+		existingIngress *networking.Ingress
+		expectedDeleted bool
+		expectedError   error
+	}{
+		{
+			name:            "unset default backend keeps ingress with rules",
+			existingIngress: ingressWithDefaultBackend(&serviceBackend, ruleHostFoo()),
+		},
+		{
+			name:            "unset default backend deletes ingress without rules",
+			existingIngress: ingressWithDefaultBackend(&serviceBackend),
+			expectedDeleted: true,
+		},
+		{
+			name:            "missing default backend returns ErrDefaultBackendNotFound",
+			existingIngress: ingressWithDefaultBackend(nil, ruleHostFoo()),
+			expectedError:   ErrDefaultBackendNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ingress *networking.Ingress
+			isIngressDeleted := false
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, test.existingIngress.DeepCopy(), nil
+			})
+			f.AddReactor("update", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				ingress = action.(clienttesting.UpdateAction).GetObject().(*networking.Ingress)
+				return true, ingress, nil
+			})
+			f.AddReactor("delete", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				isIngressDeleted = true
+				return true, nil, nil
+			})
+
+			ingressService := IngressService{
+				kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+				ingressName: "foo",
+			}
+
+			deleted, err := ingressService.UnsetDefaultBackend(context.TODO())
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError != nil {
+				return
+			}
+			assert.Equal(t, test.expectedDeleted, deleted)
+			assert.Equal(t, test.expectedDeleted, isIngressDeleted)
+			if !test.expectedDeleted {
+				assert.Nil(t, ingress.Spec.DefaultBackend)
+				assert.Equal(t, test.existingIngress.Spec.Rules, ingress.Spec.Rules)
+			}
+		})
+	}
+}
+
+func TestApplyDeleteRule_KeepsIngressWithDefaultBackend(t *testing.T) {
+	serviceBackend := CreateServiceBackend("service-bar", networking.ServiceBackendPort{Number: 80})
+	ingress := ingressWithDefaultBackend(&serviceBackend, ruleHostFoo())
+	ingress.Spec.TLS = []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}}
+
+	empty, _, err := ApplyDeleteRule(ingress, RuleSelector{ServiceName: "service-foo"})
+	assert.NoError(t, err)
+	assert.False(t, empty)
+	assert.Empty(t, ingress.Spec.Rules)
+	assert.Empty(t, ingress.Spec.TLS)
+	assert.Equal(t, &serviceBackend, ingress.Spec.DefaultBackend)
+
+	_, _, err = ApplyDeleteRule(ingress, RuleSelector{ServiceName: "service-foo"})
+	assert.Equal(t, ErrIngressRuleNotFound, err)
+}
+
+func ingressWithDefaultBackend(backend *networking.IngressBackend, rules ...networking.IngressRule) *networking.Ingress {
+	return &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: networking.IngressSpec{
+			DefaultBackend: backend,
+			Rules:          rules,
+		},
+	}
+}
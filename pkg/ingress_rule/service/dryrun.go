@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"github.com/pmezard/go-difflib/difflib"
+	"io"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+type DryRunStrategy int
+
+const (
+	// DryRunNone persists all changes.
+	DryRunNone DryRunStrategy = iota
+	// DryRunClient computes the changes locally without sending them to the api server.
+	DryRunClient
+	// DryRunServer submits the changes to the api server without persisting them.
+	DryRunServer
+)
+
+// dryRunOption returns the dry run option for requests to the api server.
+func (d DryRunStrategy) dryRunOption() []string {
+	if d == DryRunServer {
+		return []string{meta.DryRunAll}
+	}
+	return nil
+}
+
+// SetDryRun configures the service to not persist any changes.
+// Instead of persisting the changes a unified diff between the live and the resulting resource is written to out.
+func (u *updateSettings) SetDryRun(strategy DryRunStrategy, out io.Writer) {
+	u.dryRun = strategy
+	u.diffOut = out
+}
+
+// IngressDiff returns a unified diff between the live and the resulting ingress.
+// A nil ingress represents a not (or no longer) existing resource.
+func IngressDiff(live *networking.Ingress, result *networking.Ingress) (string, error) {
+	name := ""
+	if live != nil {
+		name = live.Name
+	} else if result != nil {
+		name = result.Name
+	}
+
+	liveYaml, err := ingressToDiffableYaml(live)
+	if err != nil {
+		return "", err
+	}
+	resultYaml, err := ingressToDiffableYaml(result)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(name, liveYaml, resultYaml)
+}
+
+// HTTPRouteDiff returns a unified diff between the live and the resulting HTTPRoute.
+// A nil HTTPRoute represents a not (or no longer) existing resource.
+func HTTPRouteDiff(live *gateway.HTTPRoute, result *gateway.HTTPRoute) (string, error) {
+	name := ""
+	if live != nil {
+		name = live.Name
+	} else if result != nil {
+		name = result.Name
+	}
+
+	liveYaml, err := httpRouteToDiffableYaml(live)
+	if err != nil {
+		return "", err
+	}
+	resultYaml, err := httpRouteToDiffableYaml(result)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(name, liveYaml, resultYaml)
+}
+
+// ServiceDiff returns a unified diff between the live and the resulting service.
+// A nil service represents a not (or no longer) existing resource.
+func ServiceDiff(live *core.Service, result *core.Service) (string, error) {
+	name := ""
+	if live != nil {
+		name = live.Name
+	} else if result != nil {
+		name = result.Name
+	}
+
+	liveYaml, err := serviceToDiffableYaml(live)
+	if err != nil {
+		return "", err
+	}
+	resultYaml, err := serviceToDiffableYaml(result)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(name, liveYaml, resultYaml)
+}
+
+func unifiedDiff(name string, liveYaml string, resultYaml string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(liveYaml),
+		B:        splitLines(resultYaml),
+		FromFile: fmt.Sprintf("live/%s", name),
+		ToFile:   fmt.Sprintf("dry-run/%s", name),
+		Context:  3,
+	})
+}
+
+func ingressToDiffableYaml(ingress *networking.Ingress) (string, error) {
+	if ingress == nil {
+		return "", nil
+	}
+
+	// managed fields and status are not affected by the plugin and would only clutter the diff
+	ingress = ingress.DeepCopy()
+	ingress.ManagedFields = nil
+	ingress.Status = networking.IngressStatus{}
+
+	out, err := yaml.Marshal(ingress)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func httpRouteToDiffableYaml(route *gateway.HTTPRoute) (string, error) {
+	if route == nil {
+		return "", nil
+	}
+
+	route = route.DeepCopy()
+	route.ManagedFields = nil
+	route.Status = gateway.HTTPRouteStatus{}
+
+	out, err := yaml.Marshal(route)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func serviceToDiffableYaml(service *core.Service) (string, error) {
+	if service == nil {
+		return "", nil
+	}
+
+	service = service.DeepCopy()
+	service.ManagedFields = nil
+	service.Status = core.ServiceStatus{}
+
+	out, err := yaml.Marshal(service)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// splitLines splits a yaml document into lines, an empty document results in no lines at all.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(s, "\n"))
+}
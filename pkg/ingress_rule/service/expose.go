@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	clientapps "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"strings"
+)
+
+const WorkloadDeployment = "deployment"
+const WorkloadStatefulSet = "statefulset"
+const WorkloadDaemonSet = "daemonset"
+
+// Workload is the pod template and selector of a deployment, stateful set or daemon set.
+type Workload struct {
+	Kind     string
+	Name     string
+	Labels   map[string]string
+	Selector *meta.LabelSelector
+	Template core.PodTemplateSpec
+}
+
+// ExposeService creates the services exposing workloads.
+type ExposeService struct {
+	kubeApps    clientapps.AppsV1Interface
+	kubeService clientcore.ServiceInterface
+	namespace   string
+	updateSettings
+}
+
+func NewExposeService(clientset *kubernetes.Clientset, namespace string) *ExposeService {
+	return &ExposeService{
+		kubeApps:    clientset.AppsV1(),
+		kubeService: clientset.CoreV1().Services(namespace),
+		namespace:   namespace,
+	}
+}
+
+// GetWorkload reads the workload of the kind, either WorkloadDeployment, WorkloadStatefulSet or WorkloadDaemonSet.
+func (e *ExposeService) GetWorkload(ctx context.Context, kind string, name string) (*Workload, error) {
+	workload := &Workload{Kind: kind, Name: name}
+	switch kind {
+	case WorkloadDeployment:
+		deployment, err := e.kubeApps.Deployments(e.namespace).Get(ctx, name, meta.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workload.Labels, workload.Selector, workload.Template = deployment.Labels, deployment.Spec.Selector, deployment.Spec.Template
+	case WorkloadStatefulSet:
+		statefulSet, err := e.kubeApps.StatefulSets(e.namespace).Get(ctx, name, meta.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workload.Labels, workload.Selector, workload.Template = statefulSet.Labels, statefulSet.Spec.Selector, statefulSet.Spec.Template
+	case WorkloadDaemonSet:
+		daemonSet, err := e.kubeApps.DaemonSets(e.namespace).Get(ctx, name, meta.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workload.Labels, workload.Selector, workload.Template = daemonSet.Labels, daemonSet.Spec.Selector, daemonSet.Spec.Template
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedWorkload, kind)
+	}
+	return workload, nil
+}
+
+// GetService returns the service or nil if it does not exist.
+func (e *ExposeService) GetService(ctx context.Context, name string) (*core.Service, error) {
+	service, err := e.kubeService.Get(ctx, name, meta.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	}
+	return service, err
+}
+
+// CreateService creates the service or prints the diff if dry run is enabled.
+func (e *ExposeService) CreateService(ctx context.Context, service *core.Service) error {
+	if e.dryRun == DryRunClient {
+		return e.printDiff(service)
+	}
+
+	result, err := e.kubeService.Create(ctx, service, meta.CreateOptions{DryRun: e.dryRun.dryRunOption()})
+	if err != nil || e.dryRun == DryRunNone {
+		return err
+	}
+	return e.printDiff(result)
+}
+
+// DeleteService deletes the service, it rolls back a service created by CreateService.
+func (e *ExposeService) DeleteService(ctx context.Context, name string) error {
+	return e.kubeService.Delete(ctx, name, meta.DeleteOptions{})
+}
+
+func (e *ExposeService) printDiff(result *core.Service) error {
+	if e.diffOut == nil {
+		return nil
+	}
+
+	diff, err := ServiceDiff(nil, result)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(e.diffOut, diff)
+	return err
+}
+
+// ServiceForWorkload returns a new ClusterIP service selecting the pods of the workload like 'kubectl expose'.
+// With a port the service exposes this port, targeting the target port or else the matching or only TCP container port.
+// Without a port the service mirrors the TCP container ports of the workload.
+func ServiceForWorkload(name string, workload *Workload, port int32, targetPort string) (*core.Service, error) {
+	if workload.Selector == nil || len(workload.Selector.MatchExpressions) != 0 || len(workload.Selector.MatchLabels) == 0 {
+		return nil, fmt.Errorf("%w: only selectors with matchLabels are supported", ErrUnsupportedWorkload)
+	}
+
+	var ports []core.ServicePort
+	containerPorts := tcpContainerPorts(workload.Template)
+	if port != 0 {
+		target, err := selectTargetPort(containerPorts, port, targetPort)
+		if err != nil {
+			return nil, err
+		}
+		ports = []core.ServicePort{{Name: "http", Protocol: core.ProtocolTCP, Port: port, TargetPort: target}}
+	} else {
+		if len(containerPorts) == 0 {
+			return nil, fmt.Errorf("%w: %s '%s' does not define container ports", ErrTargetPortAmbiguous, workload.Kind, workload.Name)
+		}
+		for i, containerPort := range containerPorts {
+			servicePort := core.ServicePort{
+				Name:       containerPort.Name,
+				Protocol:   core.ProtocolTCP,
+				Port:       containerPort.ContainerPort,
+				TargetPort: intstr.FromInt(int(containerPort.ContainerPort)),
+			}
+			if containerPort.Name != "" {
+				servicePort.TargetPort = intstr.FromString(containerPort.Name)
+			} else if len(containerPorts) > 1 {
+				// ports of a service with several ports have to be named
+				servicePort.Name = fmt.Sprintf("port-%d", i+1)
+			}
+			ports = append(ports, servicePort)
+		}
+	}
+
+	return &core.Service{
+		TypeMeta: meta.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name:   name,
+			Labels: workload.Labels,
+		},
+		Spec: core.ServiceSpec{
+			Type:     core.ServiceTypeClusterIP,
+			Selector: workload.Selector.MatchLabels,
+			Ports:    ports,
+		},
+	}, nil
+}
+
+// selectTargetPort returns the supplied target port or else the container port equal to the port or else the only container port.
+func selectTargetPort(containerPorts []core.ContainerPort, port int32, targetPort string) (intstr.IntOrString, error) {
+	if targetPort != "" {
+		return intstr.Parse(targetPort), nil
+	}
+
+	var defined []string
+	for _, containerPort := range containerPorts {
+		if containerPort.ContainerPort == port {
+			return intstr.FromInt(int(port)), nil
+		}
+		defined = append(defined, FormatContainerPort(containerPort))
+	}
+	if len(containerPorts) == 1 {
+		if containerPorts[0].Name != "" {
+			return intstr.FromString(containerPorts[0].Name), nil
+		}
+		return intstr.FromInt(int(containerPorts[0].ContainerPort)), nil
+	}
+
+	if len(defined) == 0 {
+		// without container ports the port is assumed to be the container port
+		return intstr.FromInt(int(port)), nil
+	}
+	return intstr.IntOrString{}, fmt.Errorf("%w: select a container port (defined: %s)", ErrTargetPortAmbiguous, strings.Join(defined, ", "))
+}
+
+// tcpContainerPorts returns the TCP ports of the containers of the pod template.
+func tcpContainerPorts(template core.PodTemplateSpec) []core.ContainerPort {
+	var ports []core.ContainerPort
+	for _, container := range template.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Protocol == "" || port.Protocol == core.ProtocolTCP {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// FormatContainerPort returns a printable representation of a container port, e.g. "http (8080)" or "8080".
+func FormatContainerPort(port core.ContainerPort) string {
+	if port.Name != "" {
+		return fmt.Sprintf("%s (%d)", port.Name, port.ContainerPort)
+	}
+	return fmt.Sprintf("%d", port.ContainerPort)
+}
+
+var ErrUnsupportedWorkload = errors.New("workload can not be exposed")
+var ErrTargetPortAmbiguous = errors.New("target port of the service is ambiguous")
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestServiceForWorkload(t *testing.T) {
+	tests := []struct {
+		name          string
+		containers    []core.ContainerPort
+		port          int32
+		targetPort    string
+		expectedPorts []core.ServicePort
+		expectedError error
+	}{
+		{
+			name:       "mirror single container port",
+			containers: []core.ContainerPort{{ContainerPort: 8080}},
+			expectedPorts: []core.ServicePort{
+				{Protocol: core.ProtocolTCP, Port: 8080, TargetPort: intstr.FromInt(8080)},
+			},
+		},
+		{
+			name:       "mirror container ports and skip udp",
+			containers: []core.ContainerPort{{Name: "http", ContainerPort: 8080}, {ContainerPort: 9090}, {Name: "dns", ContainerPort: 53, Protocol: core.ProtocolUDP}},
+			expectedPorts: []core.ServicePort{
+				{Name: "http", Protocol: core.ProtocolTCP, Port: 8080, TargetPort: intstr.FromString("http")},
+				{Name: "port-2", Protocol: core.ProtocolTCP, Port: 9090, TargetPort: intstr.FromInt(9090)},
+			},
+		},
+		{
+			name:       "port targets the only container port",
+			containers: []core.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			port:       80,
+			expectedPorts: []core.ServicePort{
+				{Name: "http", Protocol: core.ProtocolTCP, Port: 80, TargetPort: intstr.FromString("http")},
+			},
+		},
+		{
+			name:       "port targets the matching container port",
+			containers: []core.ContainerPort{{Name: "metrics", ContainerPort: 9090}, {ContainerPort: 8080}},
+			port:       8080,
+			expectedPorts: []core.ServicePort{
+				{Name: "http", Protocol: core.ProtocolTCP, Port: 8080, TargetPort: intstr.FromInt(8080)},
+			},
+		},
+		{
+			name:       "port with target port",
+			containers: []core.ContainerPort{{Name: "metrics", ContainerPort: 9090}, {Name: "web", ContainerPort: 8080}},
+			port:       80,
+			targetPort: "web",
+			expectedPorts: []core.ServicePort{
+				{Name: "http", Protocol: core.ProtocolTCP, Port: 80, TargetPort: intstr.FromString("web")},
+			},
+		},
+		{
+			name:          "ambiguous target port",
+			containers:    []core.ContainerPort{{Name: "metrics", ContainerPort: 9090}, {Name: "web", ContainerPort: 8080}},
+			port:          80,
+			expectedError: ErrTargetPortAmbiguous,
+		},
+		{
+			name:          "no container ports without port",
+			expectedError: ErrTargetPortAmbiguous,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workload := testWorkload(test.containers...)
+
+			service, err := ServiceForWorkload("foo", workload, test.port, test.targetPort)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", service.Name)
+			assert.Equal(t, core.ServiceTypeClusterIP, service.Spec.Type)
+			assert.Equal(t, map[string]string{"app": "foo"}, service.Spec.Selector)
+			assert.Equal(t, test.expectedPorts, service.Spec.Ports)
+		})
+	}
+}
+
+func TestServiceForWorkload_unsupportedSelector(t *testing.T) {
+	workload := testWorkload(core.ContainerPort{ContainerPort: 8080})
+	workload.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpExists}}
+
+	_, err := ServiceForWorkload("foo", workload, 0, "")
+	assert.ErrorIs(t, err, ErrUnsupportedWorkload)
+}
+
+func TestExposeService(t *testing.T) {
+	workload := testWorkload(core.ContainerPort{ContainerPort: 8080})
+	clientset := fake.NewSimpleClientset(&apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: workload.Labels},
+		Spec:       apps.DeploymentSpec{Selector: workload.Selector, Template: workload.Template},
+	})
+	exposeService := ExposeService{
+		kubeApps:    clientset.AppsV1(),
+		kubeService: clientset.CoreV1().Services("default"),
+		namespace:   "default",
+	}
+
+	deployment, err := exposeService.GetWorkload(context.TODO(), WorkloadDeployment, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, workload.Selector, deployment.Selector)
+
+	_, err = exposeService.GetWorkload(context.TODO(), "cronjob", "foo")
+	assert.ErrorIs(t, err, ErrUnsupportedWorkload)
+
+	service, err := ServiceForWorkload("foo", deployment, 0, "")
+	assert.NoError(t, err)
+
+	// client dry run only prints the service
+	out := &bytes.Buffer{}
+	exposeService.SetDryRun(DryRunClient, out)
+	assert.NoError(t, exposeService.CreateService(context.TODO(), service))
+	assert.Contains(t, out.String(), "+++ dry-run/foo")
+	existing, err := exposeService.GetService(context.TODO(), "foo")
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	exposeService.SetDryRun(DryRunNone, nil)
+	assert.NoError(t, exposeService.CreateService(context.TODO(), service))
+	existing, err = exposeService.GetService(context.TODO(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, service.Spec.Ports, existing.Spec.Ports)
+
+	assert.NoError(t, exposeService.DeleteService(context.TODO(), "foo"))
+	existing, err = exposeService.GetService(context.TODO(), "foo")
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func testWorkload(ports ...core.ContainerPort) *Workload {
+	labels := map[string]string{"app": "foo"}
+	return &Workload{
+		Kind:     WorkloadDeployment,
+		Name:     "foo",
+		Labels:   labels,
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		Template: core.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec:       core.PodSpec{Containers: []core.Container{{Name: "foo", Image: "foo", Ports: ports}}},
+		},
+	}
+}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayversioned "sigs.k8s.io/gateway-api/pkg/client/clientset/gateway/versioned"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/gateway/versioned/typed/apis/v1alpha2"
+	"strings"
+)
+
+// HTTPRouteService manages the rules of a Gateway API HTTPRoute with the same semantics as the IngressService.
+// The hostnames of a HTTPRoute apply to all its rules, therefore a HTTPRoute only serves a single host.
+type HTTPRouteService struct {
+	kubeRoute  gatewayclient.HTTPRouteInterface
+	routeName  string
+	parentRefs []gateway.ParentRef
+	updateSettings
+}
+
+func NewHTTPRouteService(clientset *gatewayversioned.Clientset, namespace string, routeName string, parentRefs []gateway.ParentRef) *HTTPRouteService {
+	return &HTTPRouteService{
+		kubeRoute:  clientset.GatewayV1alpha2().HTTPRoutes(namespace),
+		routeName:  routeName,
+		parentRefs: parentRefs,
+	}
+}
+
+// NewGatewayParentRef returns a reference to a gateway, empty namespace and section name are omitted.
+func NewGatewayParentRef(name string, namespace string, sectionName string) gateway.ParentRef {
+	parentRef := gateway.ParentRef{
+		Name: gateway.ObjectName(name),
+	}
+	if namespace != "" {
+		ns := gateway.Namespace(namespace)
+		parentRef.Namespace = &ns
+	}
+	if sectionName != "" {
+		section := gateway.SectionName(sectionName)
+		parentRef.SectionName = &section
+	}
+	return parentRef
+}
+
+// NewHTTPRoute returns a new HTTPRoute attached to the parents without any rules.
+func NewHTTPRoute(name string, parentRefs []gateway.ParentRef) *gateway.HTTPRoute {
+	return &gateway.HTTPRoute{
+		TypeMeta: meta.TypeMeta{
+			Kind:       "HTTPRoute",
+			APIVersion: gateway.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name: name,
+		},
+		Spec: gateway.HTTPRouteSpec{
+			CommonRouteSpec: gateway.CommonRouteSpec{
+				ParentRefs: parentRefs,
+			},
+		},
+	}
+}
+
+// create creates the HTTPRoute or prints the diff if dry run is enabled.
+func (r *HTTPRouteService) create(ctx context.Context, route *gateway.HTTPRoute) error {
+	if r.dryRun == DryRunClient {
+		return r.printDiff(nil, route)
+	}
+
+	result, err := r.kubeRoute.Create(ctx, route, meta.CreateOptions{DryRun: r.dryRun.dryRunOption()})
+	if err != nil || r.dryRun == DryRunNone {
+		return err
+	}
+	return r.printDiff(nil, result)
+}
+
+// update updates the HTTPRoute or prints the diff to the live HTTPRoute if dry run is enabled.
+func (r *HTTPRouteService) update(ctx context.Context, live *gateway.HTTPRoute, route *gateway.HTTPRoute) error {
+	if r.dryRun == DryRunClient {
+		return r.printDiff(live, route)
+	}
+
+	result, err := r.kubeRoute.Update(ctx, route, meta.UpdateOptions{DryRun: r.dryRun.dryRunOption()})
+	if err != nil || r.dryRun == DryRunNone {
+		return err
+	}
+	return r.printDiff(live, result)
+}
+
+// delete deletes the HTTPRoute or prints the diff to the live HTTPRoute if dry run is enabled.
+func (r *HTTPRouteService) delete(ctx context.Context, live *gateway.HTTPRoute) error {
+	if r.dryRun == DryRunClient {
+		return r.printDiff(live, nil)
+	}
+
+	err := r.kubeRoute.Delete(ctx, live.Name, meta.DeleteOptions{DryRun: r.dryRun.dryRunOption()})
+	if err != nil || r.dryRun == DryRunNone {
+		return err
+	}
+	return r.printDiff(live, nil)
+}
+
+func (r *HTTPRouteService) printDiff(live *gateway.HTTPRoute, result *gateway.HTTPRoute) error {
+	if r.diffOut == nil {
+		return nil
+	}
+
+	diff, err := HTTPRouteDiff(live, result)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(r.diffOut, diff)
+	return err
+}
+
+// CreateHTTPRouteRule creates a new rule for a HTTPRoute matching the path and routing to the backend service.
+// HTTPRoutes only support the path types Prefix and Exact and reference backend ports by number.
+func CreateHTTPRouteRule(path string, pathType networking.PathType, backendServiceName string, port networking.ServiceBackendPort) (*gateway.HTTPRouteRule, error) {
+	matchType, err := PathMatchType(pathType)
+	if err != nil {
+		return nil, err
+	}
+	if port.Name != "" {
+		return nil, fmt.Errorf("named port '%s' is not supported by HTTPRoute, use the port number", port.Name)
+	}
+	portNumber := gateway.PortNumber(port.Number)
+
+	return &gateway.HTTPRouteRule{
+		Matches: []gateway.HTTPRouteMatch{
+			{
+				Path: &gateway.HTTPPathMatch{
+					Type:  &matchType,
+					Value: &path,
+				},
+			},
+		},
+		BackendRefs: []gateway.HTTPBackendRef{
+			{
+				BackendRef: gateway.BackendRef{
+					BackendObjectReference: gateway.BackendObjectReference{
+						Name: gateway.ObjectName(backendServiceName),
+						Port: &portNumber,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// PathMatchType maps the path type of an ingress to the path match type of a HTTPRoute.
+func PathMatchType(pathType networking.PathType) (gateway.PathMatchType, error) {
+	switch pathType {
+	case networking.PathTypePrefix:
+		return gateway.PathMatchPathPrefix, nil
+	case networking.PathTypeExact:
+		return gateway.PathMatchExact, nil
+	default:
+		return "", fmt.Errorf("path type '%s' is not supported by HTTPRoute", pathType)
+	}
+}
+
+// AddRule adds the rule for the host to the HTTPRoute. If the HTTPRoute does not exist a new HTTPRoute attached to the parent refs will be created.
+// Conflicting concurrent modifications of the HTTPRoute are retried.
+// Returns if the resource has been created and an error
+func (r *HTTPRouteService) AddRule(ctx context.Context, host string, rule *gateway.HTTPRouteRule, overwrite bool) (created bool, err error) {
+	err = r.retryOnConflict(ctx, func(ctx context.Context) error {
+		created = false
+
+		route, err := r.kubeRoute.Get(ctx, r.routeName, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			if len(r.parentRefs) == 0 {
+				return ErrNoParentRef
+			}
+			route = NewHTTPRoute(r.routeName, nil)
+			if err = ApplyAddRouteRule(route, host, rule, r.parentRefs, false); err != nil {
+				return err
+			}
+			created = true
+			return r.create(ctx, route)
+		} else if err != nil {
+			return err
+		}
+		live := route.DeepCopy()
+
+		if err = ApplyAddRouteRule(route, host, rule, r.parentRefs, overwrite); err != nil {
+			return err
+		}
+		return r.update(ctx, live, route)
+	})
+
+	return created, err
+}
+
+// Apply creates the HTTPRoute or replaces the spec of the existing HTTPRoute with the same name.
+// Conflicting concurrent modifications of the HTTPRoute are retried.
+// Returns if the resource has been created and an error
+func (r *HTTPRouteService) Apply(ctx context.Context, desired *gateway.HTTPRoute) (created bool, err error) {
+	err = r.retryOnConflict(ctx, func(ctx context.Context) error {
+		created = false
+
+		route, err := r.kubeRoute.Get(ctx, desired.Name, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			created = true
+			return r.create(ctx, desired.DeepCopy())
+		} else if err != nil {
+			return err
+		}
+		live := route.DeepCopy()
+
+		route.Spec = *desired.Spec.DeepCopy()
+		return r.update(ctx, live, route)
+	})
+
+	return created, err
+}
+
+// ApplyAddRouteRule adds the rule for the host and the missing parent refs to the HTTPRoute without accessing the cluster.
+// Will return ErrIngressRuleAlreadyExists if the same path already routes to the same backends.
+// If the same path routes to different backends they will be replaced when overwrite is set, otherwise a BackendConflictError is returned.
+func ApplyAddRouteRule(route *gateway.HTTPRoute, host string, rule *gateway.HTTPRouteRule, parentRefs []gateway.ParentRef, overwrite bool) error {
+	if len(route.Spec.Rules) == 0 {
+		route.Spec.Hostnames = nil
+		if host != "" {
+			route.Spec.Hostnames = []gateway.Hostname{gateway.Hostname(host)}
+		}
+	} else if !servesOnlyHost(route, host) {
+		return fmt.Errorf("%w (HTTPRoute '%s' hostnames: %s)", ErrHostnameMismatch, route.Name, formatHostnames(route.Spec.Hostnames))
+	}
+
+	for _, parentRef := range parentRefs {
+		if !containsParentRef(route.Spec.ParentRefs, parentRef) {
+			route.Spec.ParentRefs = append(route.Spec.ParentRefs, parentRef)
+		}
+	}
+
+	newMatch := rule.Matches[0]
+	for i1, existing := range route.Spec.Rules {
+		matches := effectiveMatches(existing)
+		for i2, match := range matches {
+			if !pathMatchEqual(match, newMatch) {
+				continue
+			}
+			if backendRefsEqual(existing.BackendRefs, rule.BackendRefs) {
+				// exact same rule already exists
+				return ErrIngressRuleAlreadyExists
+			}
+			if !overwrite {
+				path, pathType := ingressPath(match)
+				return &BackendConflictError{
+					Host:     host,
+					Path:     path,
+					PathType: pathType,
+					Backend:  ingressBackend(existing.BackendRefs),
+				}
+			}
+			if len(matches) == 1 {
+				// replace the backends of the existing rule
+				route.Spec.Rules[i1].BackendRefs = rule.BackendRefs
+				return nil
+			}
+			// move the match to a new rule, the other matches keep their backends
+			route.Spec.Rules[i1].Matches = append(matches[:i2:i2], matches[i2+1:]...)
+			route.Spec.Rules = append(route.Spec.Rules, *rule)
+			return nil
+		}
+	}
+
+	route.Spec.Rules = append(route.Spec.Rules, *rule)
+	return nil
+}
+
+// DeleteRule removes all matches selected by the selector, rules without matches are removed.
+// Deletes the HTTPRoute if there are no rules left.
+// Conflicting concurrent modifications of the HTTPRoute are retried.
+// Returns if the resource has been deleted and an error
+func (r *HTTPRouteService) DeleteRule(ctx context.Context, selector RuleSelector) (deleted bool, err error) {
+	err = r.retryOnConflict(ctx, func(ctx context.Context) error {
+		deleted = false
+
+		route, err := r.kubeRoute.Get(ctx, r.routeName, meta.GetOptions{})
+		if err != nil {
+			return err
+		}
+		live := route.DeepCopy()
+
+		empty, err := ApplyDeleteRouteRule(route, selector)
+		if err != nil {
+			return err
+		}
+		if empty {
+			// delete HTTPRoute when the last rule is removed
+			deleted = true
+			return r.delete(ctx, live)
+		}
+
+		return r.update(ctx, live, route)
+	})
+
+	return deleted, err
+}
+
+// ApplyDeleteRouteRule removes all matches selected by the selector from the HTTPRoute without accessing the cluster.
+// The host of the selector is matched against the hostnames of the HTTPRoute, a service selector matches if any backend matches.
+// Returns if there are no rules left and an error
+func ApplyDeleteRouteRule(route *gateway.HTTPRoute, selector RuleSelector) (empty bool, err error) {
+	if selector.Host != nil && !servesHost(route, *selector.Host) {
+		return false, ErrIngressRuleNotFound
+	}
+	pathSelector := selector
+	pathSelector.Host = nil
+
+	var newRules []gateway.HTTPRouteRule
+	changed := false
+
+	for _, rule := range route.Spec.Rules {
+		var newMatches []gateway.HTTPRouteMatch
+		for _, match := range effectiveMatches(rule) {
+			if routeMatchSelected(pathSelector, match, rule.BackendRefs) {
+				changed = true
+			} else {
+				newMatches = append(newMatches, match)
+			}
+		}
+		if len(newMatches) > 0 {
+			if len(rule.Matches) > 0 {
+				rule.Matches = newMatches
+			}
+			newRules = append(newRules, rule)
+		}
+	}
+
+	if len(newRules) == 0 {
+		return true, nil
+	}
+
+	if !changed {
+		return false, ErrIngressRuleNotFound
+	}
+
+	route.Spec.Rules = newRules
+	return false, nil
+}
+
+// effectiveMatches returns the matches of the rule, a rule without matches matches all paths.
+func effectiveMatches(rule gateway.HTTPRouteRule) []gateway.HTTPRouteMatch {
+	if len(rule.Matches) > 0 {
+		return rule.Matches
+	}
+	matchType := gateway.PathMatchPathPrefix
+	path := "/"
+	return []gateway.HTTPRouteMatch{{Path: &gateway.HTTPPathMatch{Type: &matchType, Value: &path}}}
+}
+
+// ingressPath returns the path and the equivalent ingress path type of a match.
+// Regular expressions are reported as ImplementationSpecific.
+func ingressPath(match gateway.HTTPRouteMatch) (string, networking.PathType) {
+	path := "/"
+	matchType := gateway.PathMatchPathPrefix
+	if match.Path != nil {
+		if match.Path.Value != nil {
+			path = *match.Path.Value
+		}
+		if match.Path.Type != nil {
+			matchType = *match.Path.Type
+		}
+	}
+
+	switch matchType {
+	case gateway.PathMatchExact:
+		return path, networking.PathTypeExact
+	case gateway.PathMatchPathPrefix:
+		return path, networking.PathTypePrefix
+	default:
+		return path, networking.PathTypeImplementationSpecific
+	}
+}
+
+// pathMatchEqual checks if both matches only match the same path.
+func pathMatchEqual(a gateway.HTTPRouteMatch, b gateway.HTTPRouteMatch) bool {
+	if a.Headers != nil || a.QueryParams != nil || a.Method != nil || b.Headers != nil || b.QueryParams != nil || b.Method != nil {
+		return false
+	}
+	pathA, typeA := ingressPath(a)
+	pathB, typeB := ingressPath(b)
+	return pathA == pathB && typeA == typeB
+}
+
+// routeMatchSelected checks if the match with the backends is selected, a service selector matches if any backend matches.
+func routeMatchSelected(selector RuleSelector, match gateway.HTTPRouteMatch, backendRefs []gateway.HTTPBackendRef) bool {
+	path, pathType := ingressPath(match)
+	p := networking.HTTPIngressPath{Path: path, PathType: &pathType}
+	if len(backendRefs) == 0 {
+		return selector.matches("", p)
+	}
+
+	for _, backendRef := range backendRefs {
+		p.Backend = ingressBackend([]gateway.HTTPBackendRef{backendRef})
+		if selector.matches("", p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressBackend returns the first backend as ingress backend, backends without kind or of kind Service are service backends.
+func ingressBackend(backendRefs []gateway.HTTPBackendRef) networking.IngressBackend {
+	if len(backendRefs) == 0 {
+		return networking.IngressBackend{}
+	}
+
+	ref := backendRefs[0].BackendObjectReference
+	if (ref.Kind == nil || *ref.Kind == "Service") && (ref.Group == nil || *ref.Group == "") {
+		backend := &networking.IngressServiceBackend{Name: string(ref.Name)}
+		if ref.Port != nil {
+			backend.Port.Number = int32(*ref.Port)
+		}
+		return networking.IngressBackend{Service: backend}
+	}
+
+	resource := &core.TypedLocalObjectReference{Name: string(ref.Name)}
+	if ref.Kind != nil {
+		resource.Kind = string(*ref.Kind)
+	}
+	if ref.Group != nil {
+		group := string(*ref.Group)
+		resource.APIGroup = &group
+	}
+	return networking.IngressBackend{Resource: resource}
+}
+
+// backendRefsEqual compares the backends with the defaults of the Gateway API applied.
+func backendRefsEqual(a []gateway.HTTPBackendRef, b []gateway.HTTPBackendRef) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(normalizeBackendRef(a[i]), normalizeBackendRef(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeBackendRef(backendRef gateway.HTTPBackendRef) gateway.HTTPBackendRef {
+	backendRef = *backendRef.DeepCopy()
+	if backendRef.Group == nil {
+		group := gateway.Group("")
+		backendRef.Group = &group
+	}
+	if backendRef.Kind == nil {
+		kind := gateway.Kind("Service")
+		backendRef.Kind = &kind
+	}
+	if backendRef.Weight == nil {
+		weight := int32(1)
+		backendRef.Weight = &weight
+	}
+	return backendRef
+}
+
+func containsParentRef(parentRefs []gateway.ParentRef, parentRef gateway.ParentRef) bool {
+	for _, existing := range parentRefs {
+		if reflect.DeepEqual(existing, parentRef) {
+			return true
+		}
+	}
+	return false
+}
+
+// servesOnlyHost checks if the hostnames of the HTTPRoute consist of the host, an empty host requires a HTTPRoute without hostnames.
+func servesOnlyHost(route *gateway.HTTPRoute, host string) bool {
+	if host == "" {
+		return len(route.Spec.Hostnames) == 0
+	}
+	return len(route.Spec.Hostnames) == 1 && string(route.Spec.Hostnames[0]) == host
+}
+
+// servesHost checks if the host is one of the hostnames of the HTTPRoute, an empty host selects a HTTPRoute without hostnames.
+func servesHost(route *gateway.HTTPRoute, host string) bool {
+	if host == "" {
+		return len(route.Spec.Hostnames) == 0
+	}
+	for _, hostname := range route.Spec.Hostnames {
+		if string(hostname) == host {
+			return true
+		}
+	}
+	return false
+}
+
+func formatHostnames(hostnames []gateway.Hostname) string {
+	if len(hostnames) == 0 {
+		return "<all>"
+	}
+	var names []string
+	for _, hostname := range hostnames {
+		names = append(names, fmt.Sprintf("'%s'", hostname))
+	}
+	return strings.Join(names, ", ")
+}
+
+var ErrNoParentRef = errors.New("HTTPRoute does not exist, a gateway is required to create it")
+var ErrHostnameMismatch = errors.New("rules of a HTTPRoute apply to all its hostnames, use a separate HTTPRoute per host")
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	gateway "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/gateway-api/pkg/client/clientset/gateway/versioned/typed/apis/v1alpha2/fake"
+	"testing"
+)
+
+func TestHTTPRouteService_AddRule(t *testing.T) {
+	tests := []struct {
+		name              string
+		existingRoute     *gateway.HTTPRoute
+		parentRefs        []gateway.ParentRef
+		host              string
+		rule              *gateway.HTTPRouteRule
+		overwrite         bool
+		err               error
+		created           bool
+		expectedHostnames []gateway.Hostname
+		expectedRules     []gateway.HTTPRouteRule
+		expectedParents   []gateway.ParentRef
+	}{
+		{
+			name:              "add rule to new route",
+			parentRefs:        []gateway.ParentRef{NewGatewayParentRef("gw", "", "https")},
+			host:              "foo.com",
+			rule:              routeRule("/", networking.PathTypePrefix, "foo", 80),
+			created:           true,
+			expectedHostnames: []gateway.Hostname{"foo.com"},
+			expectedRules:     []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypePrefix, "foo", 80)},
+			expectedParents:   []gateway.ParentRef{NewGatewayParentRef("gw", "", "https")},
+		},
+		{
+			name: "new route requires a gateway",
+			host: "foo.com",
+			rule: routeRule("/", networking.PathTypePrefix, "foo", 80),
+			err:  ErrNoParentRef,
+		},
+		{
+			name:              "add path to existing route",
+			existingRoute:     routeHostFoo(),
+			host:              "foo.com",
+			rule:              routeRule("/api", networking.PathTypeExact, "api", 8080),
+			expectedHostnames: []gateway.Hostname{"foo.com"},
+			expectedRules: []gateway.HTTPRouteRule{
+				*routeRule("/", networking.PathTypePrefix, "foo", 80),
+				*routeRule("/api", networking.PathTypeExact, "api", 8080),
+			},
+			expectedParents: []gateway.ParentRef{NewGatewayParentRef("gw", "", "")},
+		},
+		{
+			name:              "add parent ref to existing route",
+			existingRoute:     routeHostFoo(),
+			parentRefs:        []gateway.ParentRef{NewGatewayParentRef("gw", "", ""), NewGatewayParentRef("gw2", "infra", "")},
+			host:              "foo.com",
+			rule:              routeRule("/api", networking.PathTypeExact, "api", 8080),
+			expectedHostnames: []gateway.Hostname{"foo.com"},
+			expectedRules: []gateway.HTTPRouteRule{
+				*routeRule("/", networking.PathTypePrefix, "foo", 80),
+				*routeRule("/api", networking.PathTypeExact, "api", 8080),
+			},
+			expectedParents: []gateway.ParentRef{NewGatewayParentRef("gw", "", ""), NewGatewayParentRef("gw2", "infra", "")},
+		},
+		{
+			name:          "existing rule returns ErrIngressRuleAlreadyExists",
+			existingRoute: routeHostFoo(),
+			host:          "foo.com",
+			rule:          routeRule("/", networking.PathTypePrefix, "foo", 80),
+			err:           ErrIngressRuleAlreadyExists,
+		},
+		{
+			name:          "different host returns ErrHostnameMismatch",
+			existingRoute: routeHostFoo(),
+			host:          "bar.com",
+			rule:          routeRule("/", networking.PathTypePrefix, "bar", 80),
+			err:           ErrHostnameMismatch,
+		},
+		{
+			name:          "different backend returns BackendConflictError",
+			existingRoute: routeHostFoo(),
+			host:          "foo.com",
+			rule:          routeRule("/", networking.PathTypePrefix, "bar", 80),
+			err: &BackendConflictError{
+				Host:     "foo.com",
+				Path:     "/",
+				PathType: networking.PathTypePrefix,
+				Backend:  networking.IngressBackend{Service: &networking.IngressServiceBackend{Name: "foo", Port: networking.ServiceBackendPort{Number: 80}}},
+			},
+		},
+		{
+			name:              "different backend is replaced with overwrite",
+			existingRoute:     routeHostFoo(),
+			host:              "foo.com",
+			rule:              routeRule("/", networking.PathTypePrefix, "bar", 80),
+			overwrite:         true,
+			expectedHostnames: []gateway.Hostname{"foo.com"},
+			expectedRules:     []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypePrefix, "bar", 80)},
+			expectedParents:   []gateway.ParentRef{NewGatewayParentRef("gw", "", "")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var route *gateway.HTTPRoute
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				if test.existingRoute == nil {
+					return true, nil, errors2.NewNotFound(action.GetResource().GroupResource(), action.(clienttesting.GetAction).GetName())
+				}
+				return true, test.existingRoute.DeepCopy(), nil
+			})
+			f.AddReactor("create", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				route = action.(clienttesting.CreateAction).GetObject().(*gateway.HTTPRoute)
+				return true, route, nil
+			})
+			f.AddReactor("update", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				route = action.(clienttesting.UpdateAction).GetObject().(*gateway.HTTPRoute)
+				return true, route, nil
+			})
+
+			routeService := HTTPRouteService{
+				kubeRoute:  &fake.FakeHTTPRoutes{Fake: &fake.FakeGatewayV1alpha2{Fake: &f}},
+				routeName:  "foo",
+				parentRefs: test.parentRefs,
+			}
+
+			created, err := routeService.AddRule(context.TODO(), test.host, test.rule, test.overwrite)
+			if test.err != nil {
+				var backendConflict *BackendConflictError
+				if errors.As(test.err, &backendConflict) {
+					assert.Equal(t, test.err, err)
+				} else {
+					assert.ErrorIs(t, err, test.err)
+				}
+				assert.Nil(t, route)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.created, created)
+			assert.Equal(t, test.expectedHostnames, route.Spec.Hostnames)
+			assert.Equal(t, test.expectedRules, route.Spec.Rules)
+			assert.Equal(t, test.expectedParents, route.Spec.ParentRefs)
+		})
+	}
+}
+
+func TestHTTPRouteService_DeleteRule(t *testing.T) {
+	tests := []struct {
+		name          string
+		selector      RuleSelector
+		expectedError error
+		expectedRules []gateway.HTTPRouteRule
+	}{
+		{
+			name:          "delete rule by service",
+			selector:      RuleSelector{ServiceName: "api"},
+			expectedRules: []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypePrefix, "foo", 80)},
+		},
+		{
+			name:          "delete rule by path and path type",
+			selector:      RuleSelector{Path: "/api", PathType: pathTypePtr(networking.PathTypeExact)},
+			expectedRules: []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypePrefix, "foo", 80)},
+		},
+		{
+			name:          "delete all rules deletes the route",
+			selector:      RuleSelector{Host: stringptr("foo.com")},
+			expectedRules: nil,
+		},
+		{
+			name:          "delete rule of other host returns ErrIngressRuleNotFound",
+			selector:      RuleSelector{Host: stringptr("bar.com"), ServiceName: "api"},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		{
+			name:          "delete not existing rule returns ErrIngressRuleNotFound",
+			selector:      RuleSelector{ServiceName: "bar"},
+			expectedError: ErrIngressRuleNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existing := routeHostFoo()
+			existing.Spec.Rules = append(existing.Spec.Rules, *routeRule("/api", networking.PathTypeExact, "api", 8080))
+
+			var route *gateway.HTTPRoute
+			isRouteDeleted := false
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, existing.DeepCopy(), nil
+			})
+			f.AddReactor("update", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				route = action.(clienttesting.UpdateAction).GetObject().(*gateway.HTTPRoute)
+				return true, route, nil
+			})
+			f.AddReactor("delete", "httproutes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				isRouteDeleted = true
+				return true, nil, nil
+			})
+
+			routeService := HTTPRouteService{
+				kubeRoute: &fake.FakeHTTPRoutes{Fake: &fake.FakeGatewayV1alpha2{Fake: &f}},
+				routeName: "foo",
+			}
+
+			deleted, err := routeService.DeleteRule(context.TODO(), test.selector)
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError != nil {
+				return
+			}
+
+			if len(test.expectedRules) == 0 {
+				assert.True(t, isRouteDeleted)
+				assert.True(t, deleted)
+			} else {
+				assert.False(t, deleted)
+				assert.Equal(t, test.expectedRules, route.Spec.Rules)
+			}
+		})
+	}
+}
+
+func TestCreateHTTPRouteRule(t *testing.T) {
+	rule, err := CreateHTTPRouteRule("/api", networking.PathTypeExact, "foo", networking.ServiceBackendPort{Number: 80})
+	assert.NoError(t, err)
+	assert.Len(t, rule.Matches, 1)
+	assert.Equal(t, gateway.PathMatchExact, *rule.Matches[0].Path.Type)
+	assert.Equal(t, "/api", *rule.Matches[0].Path.Value)
+	assert.Len(t, rule.BackendRefs, 1)
+	assert.Equal(t, gateway.ObjectName("foo"), rule.BackendRefs[0].Name)
+	assert.Equal(t, gateway.PortNumber(80), *rule.BackendRefs[0].Port)
+
+	_, err = CreateHTTPRouteRule("/", networking.PathTypeImplementationSpecific, "foo", networking.ServiceBackendPort{Number: 80})
+	assert.Error(t, err)
+
+	_, err = CreateHTTPRouteRule("/", networking.PathTypePrefix, "foo", networking.ServiceBackendPort{Name: "http"})
+	assert.Error(t, err)
+}
+
+func routeRule(path string, pathType networking.PathType, service string, port int32) *gateway.HTTPRouteRule {
+	rule, err := CreateHTTPRouteRule(path, pathType, service, networking.ServiceBackendPort{Number: port})
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func routeHostFoo() *gateway.HTTPRoute {
+	route := NewHTTPRoute("foo", []gateway.ParentRef{NewGatewayParentRef("gw", "", "")})
+	route.Spec.Hostnames = []gateway.Hostname{"foo.com"}
+	route.Spec.Rules = []gateway.HTTPRouteRule{*routeRule("/", networking.PathTypePrefix, "foo", 80)}
+	return route
+}
@@ -3,18 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	clientnetworking "k8s.io/client-go/kubernetes/typed/networking/v1"
-	"log"
 )
 
 type IngressService struct {
 	kubeIngress      clientnetworking.IngressInterface
 	ingressName      string
 	ingressClassName string
+	issuer           CertManagerIssuer
+	updateSettings
 }
 
 func NewIngressService(clientset *kubernetes.Clientset, namespace string, ingressName string, ingressClassName string) *IngressService {
@@ -25,39 +28,107 @@ func NewIngressService(clientset *kubernetes.Clientset, namespace string, ingres
 	}
 }
 
-func (i *IngressService) createIngress(ctx context.Context, ingressRule *networking.IngressRule, tlsSecret string) error {
-	ingressClass := &i.ingressClassName
-	if *ingressClass == "" {
-		ingressClass = nil
+// NewIngress returns a new ingress without any rules.
+// An empty ingress class name results in an ingress without ingress class.
+func NewIngress(name string, ingressClassName string) *networking.Ingress {
+	var ingressClass *string
+	if ingressClassName != "" {
+		ingressClass = &ingressClassName
 	}
 
-	ingress := &networking.Ingress{
+	return &networking.Ingress{
 		TypeMeta: meta.TypeMeta{
 			Kind:       "Ingress",
 			APIVersion: "networking.k8s.io/v1",
 		},
 		ObjectMeta: meta.ObjectMeta{
-			Name: i.ingressName,
+			Name: name,
 		},
 		Spec: networking.IngressSpec{
 			IngressClassName: ingressClass,
-			Rules:            []networking.IngressRule{*ingressRule},
 		},
 	}
+}
 
-	if tlsSecret != "" && ingressRule.Host != "" {
-		ingress.Spec.TLS = []networking.IngressTLS{{
-			Hosts:      []string{ingressRule.Host},
-			SecretName: tlsSecret,
-		}}
+// SetCertManagerIssuer configures the cert-manager issuer annotated on ingresses when rules are added.
+func (i *IngressService) SetCertManagerIssuer(issuer CertManagerIssuer) {
+	i.issuer = issuer
+}
+
+func (i *IngressService) createIngress(ctx context.Context, ingressRule *networking.IngressRule, tlsSecret string) error {
+	ingress := NewIngress(i.ingressName, i.ingressClassName)
+	if err := i.applyAddRule(ingress, ingressRule, tlsSecret, false); err != nil {
+		return err
+	}
+
+	return i.create(ctx, ingress)
+}
+
+// applyAddRule adds the rule and the tls configuration for its host and annotates the cert-manager issuer if configured.
+func (i *IngressService) applyAddRule(ingress *networking.Ingress, ingressRule *networking.IngressRule, tlsSecret string, overwrite bool) error {
+	return ApplyAddRuleWithIssuer(ingress, ingressRule, tlsSecret, overwrite, i.issuer)
+}
+
+// create creates the ingress or prints the diff if dry run is enabled.
+func (i *IngressService) create(ctx context.Context, ingress *networking.Ingress) error {
+	if i.dryRun == DryRunClient {
+		return i.printDiff(nil, ingress)
+	}
+
+	result, err := i.kubeIngress.Create(ctx, ingress, meta.CreateOptions{DryRun: i.dryRun.dryRunOption()})
+	if err != nil || i.dryRun == DryRunNone {
+		return err
+	}
+	return i.printDiff(nil, result)
+}
+
+// update updates the ingress or prints the diff to the live ingress if dry run is enabled.
+func (i *IngressService) update(ctx context.Context, live *networking.Ingress, ingress *networking.Ingress) error {
+	if i.dryRun == DryRunClient {
+		return i.printDiff(live, ingress)
+	}
+
+	result, err := i.kubeIngress.Update(ctx, ingress, meta.UpdateOptions{DryRun: i.dryRun.dryRunOption()})
+	if err != nil || i.dryRun == DryRunNone {
+		return err
+	}
+	return i.printDiff(live, result)
+}
+
+// delete deletes the ingress or prints the diff to the live ingress if dry run is enabled.
+func (i *IngressService) delete(ctx context.Context, live *networking.Ingress) error {
+	if i.dryRun == DryRunClient {
+		return i.printDiff(live, nil)
+	}
+
+	err := i.kubeIngress.Delete(ctx, live.Name, meta.DeleteOptions{DryRun: i.dryRun.dryRunOption()})
+	if err != nil || i.dryRun == DryRunNone {
+		return err
+	}
+	return i.printDiff(live, nil)
+}
+
+func (i *IngressService) printDiff(live *networking.Ingress, result *networking.Ingress) error {
+	if i.diffOut == nil {
+		return nil
 	}
 
-	_, err := i.kubeIngress.Create(ctx, ingress, meta.CreateOptions{})
+	diff, err := IngressDiff(live, result)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(i.diffOut, diff)
 	return err
 }
 
 // CreateIngressRule creates a new rule for an ingress.
-func CreateIngressRule(hostname string, path string, pathType networking.PathType, backendServiceName string, port int32) *networking.IngressRule {
+// The backend service port may either be referenced by number or by name.
+func CreateIngressRule(hostname string, path string, pathType networking.PathType, backendServiceName string, port networking.ServiceBackendPort) *networking.IngressRule {
+	return CreateIngressRuleWithBackend(hostname, path, pathType, CreateServiceBackend(backendServiceName, port))
+}
+
+// CreateIngressRuleWithBackend creates a new rule for an ingress routing the path to the backend, which is either a service or a resource.
+func CreateIngressRuleWithBackend(hostname string, path string, pathType networking.PathType, backend networking.IngressBackend) *networking.IngressRule {
 	return &networking.IngressRule{
 		Host: hostname,
 		IngressRuleValue: networking.IngressRuleValue{
@@ -65,14 +136,7 @@ func CreateIngressRule(hostname string, path string, pathType networking.PathTyp
 				Paths: []networking.HTTPIngressPath{{
 					Path:     path,
 					PathType: &pathType,
-					Backend: networking.IngressBackend{
-						Service: &networking.IngressServiceBackend{
-							Name: backendServiceName,
-							Port: networking.ServiceBackendPort{
-								Number: port, //todo add optional port configuration via PortName right here
-							},
-						},
-					},
+					Backend:  backend,
 				}},
 			},
 		},
@@ -81,73 +145,181 @@ func CreateIngressRule(hostname string, path string, pathType networking.PathTyp
 
 // AddRule configures a new backend rule.
 // If an ingress with the given name i.ingressName exists it will be updated, otherwise a new ingress will be created.
+// An existing backend for the same host, path and path type is only replaced if overwrite is set.
+// Conflicting concurrent modifications of the ingress are retried.
 // Returns if the ingress has been created and an error
-func (i *IngressService) AddRule(ctx context.Context, ingressRule *networking.IngressRule, tlsSecret string) (created bool, err error) {
+func (i *IngressService) AddRule(ctx context.Context, ingressRule *networking.IngressRule, tlsSecret string, overwrite bool) (created bool, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		created = false
+
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			// create new ingress if there is no ingress matching the criteria
+			created = true
+			return i.createIngress(ctx, ingressRule, tlsSecret)
+		} else if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		if err = i.applyAddRule(ingress, ingressRule, tlsSecret, overwrite); err != nil {
+			return err
+		}
+
+		return i.update(ctx, live, ingress)
+	})
+
+	return created, err
+}
+
+// CheckAddRule applies the rule to the current ingress without updating it, a missing ingress is checked as a new ingress.
+// It is used to detect failures of AddRule before dependent resources like tls secrets are written.
+// Returns the error AddRule would return
+func (i *IngressService) CheckAddRule(ctx context.Context, ingressRule *networking.IngressRule, tlsSecret string, overwrite bool) error {
 	ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
 	if apierror.IsNotFound(err) {
-		// create new ingress if there is no ingress matching the criteria
-		return true, i.createIngress(ctx, ingressRule, tlsSecret)
+		ingress = NewIngress(i.ingressName, i.ingressClassName)
 	} else if err != nil {
-		return false, err
+		return err
 	}
 
+	return i.applyAddRule(ingress, ingressRule, tlsSecret, overwrite)
+}
+
+// ApplyAddRule adds the rule and the tls configuration for its host to the ingress without accessing the cluster.
+// The tls configuration is added to an identical existing rule as well, ErrIngressRuleAlreadyExists is only returned if nothing changed.
+func ApplyAddRule(ingress *networking.Ingress, ingressRule *networking.IngressRule, tlsSecret string, overwrite bool) error {
 	// check if there is already a rule for this host (and add the path)
-	exists, err := addPathToExistingHostIfRuleExists(ingress, ingressRule)
-	if err != nil {
-		return false, err
+	exists, err := addPathToExistingHostIfRuleExists(ingress, ingressRule, overwrite)
+	if err == ErrIngressRuleAlreadyExists {
+		return addTlsToExistingRule(ingress, ingressRule.Host, tlsSecret)
+	} else if err != nil {
+		return err
 	}
 	if !exists {
 		// add new host rule if not existing
 		ingress.Spec.Rules = append(ingress.Spec.Rules, *ingressRule)
 	}
 
-	err = addTlsRuleIfSecretIsSupplied(ingress, ingressRule.Host, tlsSecret)
-	if err != nil {
-		return false, err
+	return addTlsRuleIfSecretIsSupplied(ingress, ingressRule.Host, tlsSecret)
+}
+
+// ApplyAddRuleWithIssuer adds the rule like ApplyAddRule and annotates the cert-manager issuer if one is supplied.
+// The issuer is annotated for an identical existing rule as well, ErrIngressRuleAlreadyExists is only returned if nothing changed.
+func ApplyAddRuleWithIssuer(ingress *networking.Ingress, ingressRule *networking.IngressRule, tlsSecret string, overwrite bool, issuer CertManagerIssuer) error {
+	err := ApplyAddRule(ingress, ingressRule, tlsSecret, overwrite)
+	if (err != nil && err != ErrIngressRuleAlreadyExists) || issuer.Name == "" {
+		return err
 	}
 
-	_, err = i.kubeIngress.Update(ctx, ingress, meta.UpdateOptions{})
-	return false, err
+	_, annotated := ingress.Annotations[issuer.Annotation()]
+	if issuerErr := ApplyCertManagerIssuer(ingress, issuer); issuerErr != nil {
+		return issuerErr
+	}
+	if !annotated {
+		return nil
+	}
+	return err
 }
 
 // addPathToExistingHostIfRuleExists checks if the ingress already contains a rule for the given host. If so, the function trys to add a new path to this rule.
-// Will return true if the rule has been added, will throw an ErrIngressRuleAlreadyExists error if the same rule (same host and path) already exists.
-func addPathToExistingHostIfRuleExists(ingress *networking.Ingress, ingressRule *networking.IngressRule) (bool, error) {
+// Will return true if the rule has been added, will throw an ErrIngressRuleAlreadyExists error if the same rule (same host, path and backend) already exists.
+// If a rule for the same host, path and path type but with a different backend exists the backend will be replaced when overwrite is set,
+// otherwise a BackendConflictError is returned.
+func addPathToExistingHostIfRuleExists(ingress *networking.Ingress, ingressRule *networking.IngressRule, overwrite bool) (bool, error) {
+	newPath := ingressRule.HTTP.Paths[0]
+
 	for i1, rule := range ingress.Spec.Rules {
-		if rule.Host == ingressRule.Host {
-			for _, path := range rule.HTTP.Paths {
-				if path.Path == ingressRule.HTTP.Paths[0].Path &&
-					*path.PathType == *ingressRule.HTTP.Paths[0].PathType &&
-					path.Backend.Service.Name == ingressRule.HTTP.Paths[0].Backend.Service.Name &&
-					path.Backend.Service.Port == ingressRule.HTTP.Paths[0].Backend.Service.Port {
-					// exact same rule already exists
-					return false, ErrIngressRuleAlreadyExists
+		if rule.Host != ingressRule.Host {
+			continue
+		}
+		if rule.HTTP == nil {
+			// rule without paths (e.g. only used for tls), add the path to it
+			ingress.Spec.Rules[i1].HTTP = &networking.HTTPIngressRuleValue{Paths: []networking.HTTPIngressPath{newPath}}
+			return true, nil
+		}
+		for i2, path := range rule.HTTP.Paths {
+			if path.Path != newPath.Path || !pathTypeEqual(path.PathType, newPath.PathType) {
+				continue
+			}
+			if equality.Semantic.DeepEqual(path.Backend, newPath.Backend) {
+				// exact same rule already exists
+				return false, ErrIngressRuleAlreadyExists
+			}
+			if !overwrite {
+				return false, &BackendConflictError{
+					Host:     rule.Host,
+					Path:     path.Path,
+					PathType: *newPath.PathType,
+					Backend:  path.Backend,
 				}
 			}
-			// add rule to for existing host
-			ingress.Spec.Rules[i1].HTTP.Paths = append(ingress.Spec.Rules[i1].HTTP.Paths, ingressRule.HTTP.Paths[0])
+			// replace the backend of the existing path
+			ingress.Spec.Rules[i1].HTTP.Paths[i2].Backend = newPath.Backend
 			return true, nil
 		}
+		// add rule to for existing host
+		ingress.Spec.Rules[i1].HTTP.Paths = append(ingress.Spec.Rules[i1].HTTP.Paths, newPath)
+		return true, nil
 	}
 
 	return false, nil
 }
 
-// DeleteRule removes the rule by service name or service name and port.
-// Returns if the resource has been deleted and an error
-func (i *IngressService) DeleteRule(ctx context.Context, serviceName string, servicePort int32) (deleted bool, err error) {
-	ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
-	if err != nil {
-		return false, err
+// pathTypeEqual compares two optional path types, a missing path type only equals another missing path type.
+func pathTypeEqual(a *networking.PathType, b *networking.PathType) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
+
+// DeleteRule removes all paths matching the selector.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns if the resource has been deleted, the hosts removed from the tls configuration and an error
+func (i *IngressService) DeleteRule(ctx context.Context, selector RuleSelector) (deleted bool, tlsChanges []TlsChange, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		deleted = false
+
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		var empty bool
+		empty, tlsChanges, err = ApplyDeleteRule(ingress, selector)
+		if err != nil {
+			return err
+		}
+		if empty {
+			// delete ingress when the last rule is removed
+			deleted = true
+			return i.delete(ctx, live)
+		}
+
+		return i.update(ctx, live, ingress)
+	})
+
+	return deleted, tlsChanges, err
+}
 
+// ApplyDeleteRule removes all paths matching the selector from the ingress without accessing the cluster.
+// The tls entries of hosts without rules are removed, the cert-manager issuer annotations once no tls entry is left.
+// Returns if there are no rules and no default backend left, the hosts removed from the tls configuration and an error
+func ApplyDeleteRule(ingress *networking.Ingress, selector RuleSelector) (empty bool, tlsChanges []TlsChange, err error) {
 	var newRules []networking.IngressRule
 	changed := false
 
 	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			// rules without paths can not be selected
+			newRules = append(newRules, rule)
+			continue
+		}
 		var newPaths []networking.HTTPIngressPath
 		for _, p := range rule.HTTP.Paths {
-			if !(p.Backend.Service.Name == serviceName && (servicePort == 0 || p.Backend.Service.Port.Number == servicePort)) {
+			if !selector.matches(rule.Host, p) {
 				newPaths = append(newPaths, p)
 			} else {
 				changed = true
@@ -159,19 +331,42 @@ func (i *IngressService) DeleteRule(ctx context.Context, serviceName string, ser
 		}
 	}
 
-	if len(newRules) == 0 {
-		// delete ingress when the last rule is removed
-		return true, i.kubeIngress.Delete(ctx, ingress.Name, meta.DeleteOptions{})
+	// an ingress with default backend keeps serving without rules
+	if len(newRules) == 0 && ingress.Spec.DefaultBackend == nil {
+		return true, nil, nil
+	}
+
+	if !changed {
+		return false, nil, ErrIngressRuleNotFound
 	}
 
-	if changed {
-		ingress.Spec.Rules = newRules
-		deleteTlsRulesForNoLongerExistingHosts(ingress)
-		_, err = i.kubeIngress.Update(ctx, ingress, meta.UpdateOptions{})
-		return false, err
+	ingress.Spec.Rules = newRules
+	tlsChanges = ReconcileTls(ingress)
+	removeCertManagerIssuerWithoutTls(ingress)
+	return false, tlsChanges, nil
+}
+
+// addTlsToExistingRule adds the tls configuration for the host of an identical existing rule.
+// Returns ErrIngressRuleAlreadyExists if the tls configuration is not supplied or already exists.
+func addTlsToExistingRule(ingress *networking.Ingress, host string, tlsSecret string) error {
+	tls := len(ingress.Spec.TLS)
+	hosts := tlsHostCount(ingress)
+	if err := addTlsRuleIfSecretIsSupplied(ingress, host, tlsSecret); err != nil {
+		return err
 	}
+	if len(ingress.Spec.TLS) == tls && tlsHostCount(ingress) == hosts {
+		return ErrIngressRuleAlreadyExists
+	}
+	return nil
+}
 
-	return false, ErrIngressRuleNotFound
+// tlsHostCount returns the number of hosts of all tls entries.
+func tlsHostCount(ingress *networking.Ingress) int {
+	count := 0
+	for _, tlsEntry := range ingress.Spec.TLS {
+		count += len(tlsEntry.Hosts)
+	}
+	return count
 }
 
 func addTlsRuleIfSecretIsSupplied(ingress *networking.Ingress, host string, tlsSecret string) error {
@@ -210,32 +405,19 @@ func addTlsRuleIfSecretIsSupplied(ingress *networking.Ingress, host string, tlsS
 	return nil
 }
 
-func deleteTlsRulesForNoLongerExistingHosts(ingress *networking.Ingress) {
-	var newTlsConfig []networking.IngressTLS
-
-	for i, tlsEntry := range ingress.Spec.TLS {
-		for i2, host := range tlsEntry.Hosts {
-			hostExists := false
-			for _, rule := range ingress.Spec.Rules {
-				if rule.Host == host {
-					hostExists = true
-				}
-			}
-			if !hostExists {
-				ingress.Spec.TLS[i].Hosts[i2] = ingress.Spec.TLS[i].Hosts[len(ingress.Spec.TLS[i].Hosts)-1]
-				ingress.Spec.TLS[i].Hosts = ingress.Spec.TLS[i].Hosts[:len(ingress.Spec.TLS[i].Hosts)-1]
-				break
-			}
-		}
-		log.Printf("%+v\n", ingress.Spec.TLS[i].Hosts)
-		if len(ingress.Spec.TLS[i].Hosts) != 0 {
-			newTlsConfig = append(newTlsConfig, ingress.Spec.TLS[i])
-		}
-	}
+var ErrIngressRuleAlreadyExists = errors.New("ingress rule already exists")
+var ErrIngressRuleNotFound = errors.New("could not find matching ingress rule")
+var ErrTlsConfigurationAlreadyExists = errors.New("tls configuration for hostname already exists")
 
-	ingress.Spec.TLS = newTlsConfig
+// BackendConflictError is returned when a rule for the same host, path and path type already routes to a different backend.
+type BackendConflictError struct {
+	Host     string
+	Path     string
+	PathType networking.PathType
+	Backend  networking.IngressBackend
 }
 
-var ErrIngressRuleAlreadyExists = errors.New("ingress rule already exists")
-var ErrIngressRuleNotFound = errors.New("could not find ingress rule for service")
-var ErrTlsConfigurationAlreadyExists = errors.New("tls configuration for hostname already exists")
+func (e *BackendConflictError) Error() string {
+	return fmt.Sprintf("rule for host '%s' with path '%s' (path type: '%s') already exists with backend %s",
+		e.Host, e.Path, e.PathType, FormatBackend(e.Backend))
+}
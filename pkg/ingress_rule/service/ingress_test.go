@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	networking "k8s.io/api/networking/v1"
 	errors2 "k8s.io/apimachinery/pkg/api/errors"
@@ -9,7 +10,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/typed/networking/v1/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIngressService_AddRuleToNewIngress(t *testing.T) {
@@ -77,7 +80,7 @@ func TestIngressService_AddRuleToNewIngress(t *testing.T) {
 				ingressClassName: test.ingressClassName,
 			}
 
-			created, err := ingressService.AddRule(context.TODO(), &test.newRule, test.tlsSecret)
+			created, err := ingressService.AddRule(context.TODO(), &test.newRule, test.tlsSecret, false)
 			assert.Equal(t, test.err, err)
 			assert.Equal(t, test.ingressCreated, created)
 
@@ -96,6 +99,7 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 		expectedRules            []networking.IngressRule
 		err                      error
 		tlsSecret                string
+		overwrite                bool
 		expectedTlsConfiguration []networking.IngressTLS
 		initialTlsConfiguration  []networking.IngressTLS
 	}{
@@ -118,6 +122,46 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 			expectedRules: []networking.IngressRule{ruleHostFoo()},
 			err:           ErrIngressRuleAlreadyExists,
 		},
+		{
+			name:          "add rule with named port to existing ingress with existing rule with same port name",
+			existingRules: []networking.IngressRule{ruleHostFooNamedPort()},
+			newRule:       ruleHostFooNamedPort(),
+			expectedRules: []networking.IngressRule{ruleHostFooNamedPort()},
+			err:           ErrIngressRuleAlreadyExists,
+		},
+		{
+			name:          "add rule with different backend to existing ingress with existing host and path",
+			existingRules: []networking.IngressRule{ruleHostFoo()},
+			newRule:       ruleHostFooServiceBaz(),
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+			err: &BackendConflictError{
+				Host:     "foo.com",
+				Path:     "/",
+				PathType: networking.PathTypePrefix,
+				Backend:  ruleHostFoo().HTTP.Paths[0].Backend,
+			},
+		},
+		{
+			name:          "overwrite backend of existing ingress with existing host and path",
+			existingRules: []networking.IngressRule{ruleHostFooTwoRules()},
+			newRule:       ruleHostFooServiceBaz(),
+			overwrite:     true,
+			expectedRules: []networking.IngressRule{ruleHostFooServiceBazAndFoo2()},
+		},
+		{
+			name:          "overwrite backend with same backend of existing ingress with existing host and path",
+			existingRules: []networking.IngressRule{ruleHostFoo()},
+			newRule:       ruleHostFoo(),
+			overwrite:     true,
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+			err:           ErrIngressRuleAlreadyExists,
+		},
+		{
+			name:          "add rule with different backend and different path type to existing ingress with existing host and path",
+			existingRules: []networking.IngressRule{ruleHostFoo()},
+			newRule:       ruleHostFooExactServiceBaz(),
+			expectedRules: []networking.IngressRule{ruleHostFooAndExactServiceBaz()},
+		},
 		{
 			name:          "add rule to exiting ingress with tls secret",
 			existingRules: []networking.IngressRule{ruleHostBar()},
@@ -140,6 +184,17 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 				SecretName: "my-secret",
 			}},
 		},
+		{
+			name:          "add tls secret to existing ingress with existing rule",
+			existingRules: []networking.IngressRule{ruleHostFoo()},
+			newRule:       ruleHostFoo(),
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+			tlsSecret:     "my-secret",
+			expectedTlsConfiguration: []networking.IngressTLS{{
+				Hosts:      []string{ruleHostFoo().Host},
+				SecretName: "my-secret",
+			}},
+		},
 		{
 			name:          "add rule to existing ingress with existing rule with tls secret",
 			existingRules: []networking.IngressRule{ruleHostFoo()},
@@ -147,6 +202,30 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 			expectedRules: []networking.IngressRule{ruleHostFoo()},
 			err:           ErrIngressRuleAlreadyExists,
 			tlsSecret:     "my-secret",
+			initialTlsConfiguration: []networking.IngressTLS{{
+				Hosts:      []string{ruleHostFoo().Host},
+				SecretName: "my-secret",
+			}},
+			expectedTlsConfiguration: []networking.IngressTLS{{
+				Hosts:      []string{ruleHostFoo().Host},
+				SecretName: "my-secret",
+			}},
+		},
+		{
+			name:          "add rule to existing ingress with existing rule with other tls secret returns ErrTlsConfigurationAlreadyExists",
+			existingRules: []networking.IngressRule{ruleHostFoo()},
+			newRule:       ruleHostFoo(),
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+			err:           ErrTlsConfigurationAlreadyExists,
+			tlsSecret:     "my-secret",
+			initialTlsConfiguration: []networking.IngressTLS{{
+				Hosts:      []string{ruleHostFoo().Host},
+				SecretName: "my-old-secret",
+			}},
+			expectedTlsConfiguration: []networking.IngressTLS{{
+				Hosts:      []string{ruleHostFoo().Host},
+				SecretName: "my-old-secret",
+			}},
 		},
 		{
 			name:          "add rule to existing ingress with existing rule with tls secret returns ErrTlsConfigurationAlreadyExists",
@@ -226,7 +305,7 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 				ingressName: "foo",
 			}
 
-			created, err := ingressService.AddRule(context.TODO(), &test.newRule, test.tlsSecret)
+			created, err := ingressService.AddRule(context.TODO(), &test.newRule, test.tlsSecret, test.overwrite)
 			assert.Equal(t, test.err, err)
 			assert.False(t, created)
 
@@ -240,20 +319,24 @@ func TestIngressService_AddRuleToExistingIngress(t *testing.T) {
 
 func TestIngressService_DeleteRule(t *testing.T) {
 	tests := []struct {
-		name              string
-		inputRules        []networking.IngressRule
-		serviceName       string
-		servicePort       int32
-		expectedRules     []networking.IngressRule
-		expectedError     error
-		initialTlsConfig  []networking.IngressTLS
-		expectedTlsConfig []networking.IngressTLS
+		name               string
+		inputRules         []networking.IngressRule
+		serviceName        string
+		servicePort        networking.ServiceBackendPort
+		host               *string
+		path               string
+		pathType           *networking.PathType
+		expectedRules      []networking.IngressRule
+		expectedError      error
+		initialTlsConfig   []networking.IngressTLS
+		expectedTlsConfig  []networking.IngressTLS
+		expectedTlsChanges []TlsChange
 	}{
 		{
 			name:          "delete last rule by service name",
 			inputRules:    []networking.IngressRule{ruleHostFoo()},
 			serviceName:   "service-foo",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{},
 			expectedError: nil,
 		},
@@ -261,7 +344,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete last rule by service name and port",
 			inputRules:    []networking.IngressRule{ruleHostFoo()},
 			serviceName:   "service-foo",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{},
 			expectedError: nil,
 		},
@@ -269,7 +352,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete last rule by service name with invalid name",
 			inputRules:    []networking.IngressRule{ruleHostFoo()},
 			serviceName:   "service-bar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFoo()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -277,7 +360,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete last rule by service name and port with invalid port",
 			inputRules:    []networking.IngressRule{ruleHostFoo()},
 			serviceName:   "service-foo",
-			servicePort:   81,
+			servicePort:   networking.ServiceBackendPort{Number: 81},
 			expectedRules: []networking.IngressRule{ruleHostFoo()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -285,7 +368,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete last rule by service name and port with invalid name",
 			inputRules:    []networking.IngressRule{ruleHostFoo()},
 			serviceName:   "service-bar",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostFoo()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -294,7 +377,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name",
 			inputRules:    []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostBar()},
 			expectedError: nil,
 		},
@@ -302,7 +385,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name and port",
 			inputRules:    []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostBar()},
 			expectedError: nil,
 		},
@@ -310,7 +393,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete rule by service name",
 			inputRules:    []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			serviceName:   "service-fooBar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -318,7 +401,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete rule by service name and port with invalid name",
 			inputRules:    []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			serviceName:   "service-fooBar",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -326,7 +409,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete rule by service name and port with invalid port",
 			inputRules:    []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   81,
+			servicePort:   networking.ServiceBackendPort{Number: 81},
 			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -334,7 +417,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name multiple paths 1",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFoo2(), ruleHostBar()},
 			expectedError: nil,
 		},
@@ -342,7 +425,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name multiple paths 2",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-foo-2",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			expectedError: nil,
 		},
@@ -350,7 +433,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name multiple paths 3",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-bar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules()},
 			expectedError: nil,
 		},
@@ -358,7 +441,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name and port multiple paths",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostFoo2(), ruleHostBar()},
 			expectedError: nil,
 		},
@@ -366,7 +449,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name and port multiple paths 1",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-foo-2",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
 			expectedError: nil,
 		},
@@ -374,7 +457,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "delete rule by service name and port multiple paths 3",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-bar",
-			servicePort:   80,
+			servicePort:   networking.ServiceBackendPort{Number: 80},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules()},
 			expectedError: nil,
 		},
@@ -382,7 +465,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete rule by service name multiple paths with invalid name",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-fooBar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -390,7 +473,96 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do not delete rule by service name multiple paths with invalid port",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-foo",
-			servicePort:   81,
+			servicePort:   networking.ServiceBackendPort{Number: 81},
+			expectedRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		// test cases for named ports
+		{
+			name:          "delete rule by service name and port name",
+			inputRules:    []networking.IngressRule{ruleHostFooNamedPort(), ruleHostBar()},
+			serviceName:   "service-foo",
+			servicePort:   networking.ServiceBackendPort{Name: "http"},
+			expectedRules: []networking.IngressRule{ruleHostBar()},
+			expectedError: nil,
+		},
+		{
+			name:          "do not delete rule by service name and port name with invalid port name",
+			inputRules:    []networking.IngressRule{ruleHostFooNamedPort(), ruleHostBar()},
+			serviceName:   "service-foo",
+			servicePort:   networking.ServiceBackendPort{Name: "grpc"},
+			expectedRules: []networking.IngressRule{ruleHostFooNamedPort(), ruleHostBar()},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		{
+			name:          "do not delete rule by service name and port number with named port",
+			inputRules:    []networking.IngressRule{ruleHostFooNamedPort(), ruleHostBar()},
+			serviceName:   "service-foo",
+			servicePort:   networking.ServiceBackendPort{Number: 80},
+			expectedRules: []networking.IngressRule{ruleHostFooNamedPort(), ruleHostBar()},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		// test cases for host, path and path type selectors
+		{
+			name:          "delete rule by host",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			expectedRules: []networking.IngressRule{ruleHostBar()},
+			expectedError: nil,
+		},
+		{
+			name:          "delete rule by host and path",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			path:          "/2",
+			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedError: nil,
+		},
+		{
+			name:          "delete rule by path",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			path:          "/",
+			expectedRules: []networking.IngressRule{ruleHostFoo2()},
+			expectedError: nil,
+		},
+		{
+			name:          "delete rule by host, path and path type",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			path:          "/",
+			pathType:      pathTypePtr(networking.PathTypePrefix),
+			expectedRules: []networking.IngressRule{ruleHostFoo2(), ruleHostBar()},
+			expectedError: nil,
+		},
+		{
+			name:          "delete rule by host and service name",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			serviceName:   "service-foo-2",
+			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedError: nil,
+		},
+		{
+			name:          "do not delete rule by host and service name of other host",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			serviceName:   "service-bar",
+			expectedRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		{
+			name:          "do not delete rule by host, path and different path type",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr("foo.com"),
+			path:          "/",
+			pathType:      pathTypePtr(networking.PathTypeExact),
+			expectedRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			expectedError: ErrIngressRuleNotFound,
+		},
+		{
+			name:          "do not delete rule by empty host",
+			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			host:          stringptr(""),
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			expectedError: ErrIngressRuleNotFound,
 		},
@@ -399,7 +571,7 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			name:          "do delete tls configuration when host no longer exists (shared secret)",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-bar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules()},
 			expectedError: nil,
 			initialTlsConfig: []networking.IngressTLS{{
@@ -410,12 +582,13 @@ func TestIngressService_DeleteRule(t *testing.T) {
 				Hosts:      []string{"foo.com"},
 				SecretName: "my-secret",
 			}},
+			expectedTlsChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret", Removed: true}},
 		},
 		{
 			name:          "do delete tls configuration when host no longer exists (own secret)",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-bar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules()},
 			expectedError: nil,
 			initialTlsConfig: []networking.IngressTLS{{
@@ -430,19 +603,21 @@ func TestIngressService_DeleteRule(t *testing.T) {
 				Hosts:      []string{"foo.com"},
 				SecretName: "my-secret",
 			}},
+			expectedTlsChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret2", Removed: true}},
 		},
 		{
 			name:          "do delete tls configuration when host no longer exists (last secret)",
 			inputRules:    []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
 			serviceName:   "service-bar",
-			servicePort:   0,
+			servicePort:   networking.ServiceBackendPort{},
 			expectedRules: []networking.IngressRule{ruleHostFooTwoRules()},
 			expectedError: nil,
 			initialTlsConfig: []networking.IngressTLS{{
 				Hosts:      []string{"bar.com"},
 				SecretName: "my-secret2",
 			}},
-			expectedTlsConfig: nil,
+			expectedTlsConfig:  nil,
+			expectedTlsChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret2", Removed: true}},
 		},
 	}
 	for _, test := range tests {
@@ -473,7 +648,13 @@ func TestIngressService_DeleteRule(t *testing.T) {
 				ingressName: "foo",
 			}
 
-			deleted, err := ingressService.DeleteRule(context.TODO(), test.serviceName, test.servicePort)
+			deleted, tlsChanges, err := ingressService.DeleteRule(context.TODO(), RuleSelector{
+				Host:        test.host,
+				Path:        test.path,
+				PathType:    test.pathType,
+				ServiceName: test.serviceName,
+				ServicePort: test.servicePort,
+			})
 			assert.Equal(t, test.expectedError, err)
 
 			if len(test.expectedRules) == 0 {
@@ -482,13 +663,192 @@ func TestIngressService_DeleteRule(t *testing.T) {
 			} else {
 				assert.Equal(t, test.expectedRules, ingress.Spec.Rules)
 				assert.Equal(t, test.expectedTlsConfig, ingress.Spec.TLS)
+				assert.Equal(t, test.expectedTlsChanges, tlsChanges)
 			}
 		})
 	}
 }
 
+func TestIngressService_CheckAddRule(t *testing.T) {
+	existing := true
+	f := clienttesting.Fake{}
+	f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		if !existing {
+			return true, nil, errors2.NewNotFound(networking.Resource("ingresses"), "foo")
+		}
+		return true, &networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec:       networking.IngressSpec{Rules: []networking.IngressRule{ruleHostFoo()}},
+		}, nil
+	})
+	ingressService := IngressService{
+		kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+		ingressName: "foo",
+	}
+
+	rule := ruleHostBar()
+	assert.NoError(t, ingressService.CheckAddRule(context.TODO(), &rule, "my-secret", false))
+	conflict := ruleHostFooServiceBaz()
+	assert.IsType(t, &BackendConflictError{}, ingressService.CheckAddRule(context.TODO(), &conflict, "my-secret", false))
+	assert.NoError(t, ingressService.CheckAddRule(context.TODO(), &conflict, "my-secret", true))
+	// the tls secret of --tls-cert-file is attached to an identical existing rule
+	same := ruleHostFoo()
+	assert.NoError(t, ingressService.CheckAddRule(context.TODO(), &same, "my-secret", false))
+	assert.Equal(t, ErrIngressRuleAlreadyExists, ingressService.CheckAddRule(context.TODO(), &same, "", false))
+
+	existing = false
+	assert.NoError(t, ingressService.CheckAddRule(context.TODO(), &conflict, "my-secret", false))
+	for _, action := range f.Actions() {
+		assert.Equal(t, "get", action.GetVerb())
+	}
+}
+
 func TestCreateIngressRule(t *testing.T) {
-	assert.Equal(t, ruleHostFoo2(), *CreateIngressRule("foo.com", "/2", networking.PathTypePrefix, "service-foo-2", 80))
+	assert.Equal(t, ruleHostFoo2(), *CreateIngressRule("foo.com", "/2", networking.PathTypePrefix, "service-foo-2", networking.ServiceBackendPort{Number: 80}))
+
+	namedPortRule := ruleHostFoo2()
+	namedPortRule.HTTP.Paths[0].Backend.Service.Port = networking.ServiceBackendPort{Name: "http"}
+	assert.Equal(t, namedPortRule, *CreateIngressRule("foo.com", "/2", networking.PathTypePrefix, "service-foo-2", networking.ServiceBackendPort{Name: "http"}))
+}
+
+func TestCreateIngressRuleWithBackend(t *testing.T) {
+	backend := CreateResourceBackend("k8s.example.com", "StorageBucket", "static-assets")
+	rule := CreateIngressRuleWithBackend("foo.com", "/static", networking.PathTypePrefix, backend)
+
+	assert.Equal(t, "foo.com", rule.Host)
+	assert.Equal(t, backend, rule.HTTP.Paths[0].Backend)
+	assert.Nil(t, rule.HTTP.Paths[0].Backend.Service)
+}
+
+func TestApplyAddRule_MixedBackends(t *testing.T) {
+	resourceBackend := CreateResourceBackend("k8s.example.com", "StorageBucket", "static-assets")
+	resourceRule := *CreateIngressRuleWithBackend("foo.com", "/", networking.PathTypePrefix, resourceBackend)
+	hostOnlyRule := networking.IngressRule{Host: "foo.com"}
+
+	tests := []struct {
+		name          string
+		existingRules []networking.IngressRule
+		newRule       networking.IngressRule
+		overwrite     bool
+		expectedRules []networking.IngressRule
+		expectedError error
+	}{
+		{
+			name:          "add resource backend to existing host",
+			existingRules: []networking.IngressRule{ruleHostFoo2()},
+			newRule:       resourceRule,
+			expectedRules: []networking.IngressRule{{
+				Host: "foo.com",
+				IngressRuleValue: networking.IngressRuleValue{HTTP: &networking.HTTPIngressRuleValue{Paths: []networking.HTTPIngressPath{
+					ruleHostFoo2().HTTP.Paths[0],
+					resourceRule.HTTP.Paths[0],
+				}}},
+			}},
+		},
+		{
+			name:          "same resource backend returns ErrIngressRuleAlreadyExists",
+			existingRules: []networking.IngressRule{resourceRule},
+			newRule:       *CreateIngressRuleWithBackend("foo.com", "/", networking.PathTypePrefix, resourceBackend),
+			expectedError: ErrIngressRuleAlreadyExists,
+		},
+		{
+			name:          "service backend conflicts with resource backend",
+			existingRules: []networking.IngressRule{resourceRule},
+			newRule:       ruleHostFoo(),
+			expectedError: &BackendConflictError{Host: "foo.com", Path: "/", PathType: networking.PathTypePrefix, Backend: resourceBackend},
+		},
+		{
+			name:          "overwrite resource backend with service backend",
+			existingRules: []networking.IngressRule{resourceRule},
+			newRule:       ruleHostFoo(),
+			overwrite:     true,
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+		},
+		{
+			name:          "add path to rule without paths",
+			existingRules: []networking.IngressRule{hostOnlyRule, ruleHostBar()},
+			newRule:       ruleHostFoo(),
+			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+		},
+		{
+			name: "path without path type does not match",
+			existingRules: []networking.IngressRule{{
+				Host: "foo.com",
+				IngressRuleValue: networking.IngressRuleValue{HTTP: &networking.HTTPIngressRuleValue{Paths: []networking.HTTPIngressPath{
+					{Path: "/", Backend: resourceBackend},
+				}}},
+			}},
+			newRule: ruleHostFoo(),
+			expectedRules: []networking.IngressRule{{
+				Host: "foo.com",
+				IngressRuleValue: networking.IngressRuleValue{HTTP: &networking.HTTPIngressRuleValue{Paths: []networking.HTTPIngressPath{
+					{Path: "/", Backend: resourceBackend},
+					ruleHostFoo().HTTP.Paths[0],
+				}}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{Spec: networking.IngressSpec{Rules: test.existingRules}}
+
+			err := ApplyAddRule(ingress, &test.newRule, "", test.overwrite)
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				assert.Equal(t, test.expectedRules, ingress.Spec.Rules)
+			}
+		})
+	}
+}
+
+func TestApplyDeleteRule_MixedBackends(t *testing.T) {
+	resourceBackend := CreateResourceBackend("", "ConfigMap", "static-assets")
+	resourceRule := *CreateIngressRuleWithBackend("foo.com", "/static", networking.PathTypePrefix, resourceBackend)
+	hostOnlyRule := networking.IngressRule{Host: "bar.com"}
+
+	tests := []struct {
+		name          string
+		selector      RuleSelector
+		expectedEmpty bool
+		expectedRules []networking.IngressRule
+		expectedError error
+	}{
+		{
+			name:          "delete by service keeps resource backends and rules without paths",
+			selector:      RuleSelector{ServiceName: "service-foo"},
+			expectedRules: []networking.IngressRule{resourceRule, hostOnlyRule},
+		},
+		{
+			name:          "delete resource backend by path",
+			selector:      RuleSelector{Path: "/static"},
+			expectedRules: []networking.IngressRule{ruleHostFoo(), hostOnlyRule},
+		},
+		{
+			name:          "rules without paths are not selected by host",
+			selector:      RuleSelector{Host: stringptr("bar.com")},
+			expectedError: ErrIngressRuleNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{Spec: networking.IngressSpec{Rules: []networking.IngressRule{ruleHostFoo(), resourceRule, hostOnlyRule}}}
+
+			empty, _, err := ApplyDeleteRule(ingress, test.selector)
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				assert.Equal(t, test.expectedEmpty, empty)
+				assert.Equal(t, test.expectedRules, ingress.Spec.Rules)
+			}
+		})
+	}
+}
+
+func stringptr(val string) *string {
+	return &val
+}
+
+func pathTypePtr(val networking.PathType) *networking.PathType {
+	return &val
 }
 
 func ruleHostFoo() networking.IngressRule {
@@ -599,3 +959,275 @@ func ruleHostBar() networking.IngressRule {
 		},
 	}
 }
+
+func ruleHostFooNamedPort() networking.IngressRule {
+	rule := ruleHostFoo()
+	rule.HTTP.Paths[0].Backend.Service.Port = networking.ServiceBackendPort{
+		Name: "http",
+	}
+
+	return rule
+}
+
+func TestIngressService_DryRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       DryRunStrategy
+		existingRules  []networking.IngressRule
+		add            bool
+		expectedAction string
+		expectedDiff   []string
+	}{
+		{
+			name:           "client dry run does not create ingress",
+			strategy:       DryRunClient,
+			add:            true,
+			expectedAction: "",
+			expectedDiff:   []string{"--- live/foo", "+++ dry-run/foo", "+  name: foo", "+  - host: foo.com"},
+		},
+		{
+			name:           "client dry run does not update ingress",
+			strategy:       DryRunClient,
+			existingRules:  []networking.IngressRule{ruleHostBar()},
+			add:            true,
+			expectedAction: "",
+			expectedDiff:   []string{"+  - host: foo.com", "+        path: /"},
+		},
+		{
+			name:           "client dry run does not delete ingress",
+			strategy:       DryRunClient,
+			existingRules:  []networking.IngressRule{ruleHostFoo()},
+			expectedAction: "",
+			expectedDiff:   []string{"-  - host: foo.com", "-  name: foo"},
+		},
+		{
+			name:           "server dry run updates ingress with dry run option",
+			strategy:       DryRunServer,
+			existingRules:  []networking.IngressRule{ruleHostBar()},
+			add:            true,
+			expectedAction: "update",
+			expectedDiff:   []string{"+  - host: foo.com"},
+		},
+		{
+			name:           "server dry run deletes ingress with dry run option",
+			strategy:       DryRunServer,
+			existingRules:  []networking.IngressRule{ruleHostFoo()},
+			expectedAction: "delete",
+			expectedDiff:   []string{"-  - host: foo.com"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action := ""
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "ingresses", func(a clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				if test.existingRules == nil {
+					return true, nil, errors2.NewNotFound(a.GetResource().GroupResource(), a.(clienttesting.GetAction).GetName())
+				}
+				return true, &networking.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+					Spec:       networking.IngressSpec{Rules: test.existingRules},
+				}, nil
+			})
+			f.AddReactor("*", "ingresses", func(a clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				action = a.GetVerb()
+				switch a := a.(type) {
+				case clienttesting.UpdateActionImpl:
+					return true, a.GetObject(), nil
+				case clienttesting.CreateActionImpl:
+					return true, a.GetObject(), nil
+				case clienttesting.DeleteActionImpl:
+					// the fake client only records the options of delete requests
+					assert.Equal(t, []string{metav1.DryRunAll}, a.DeleteOptions.DryRun)
+				}
+				return true, nil, nil
+			})
+
+			diff := &strings.Builder{}
+			ingressService := IngressService{
+				kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+				ingressName: "foo",
+			}
+			ingressService.SetDryRun(test.strategy, diff)
+
+			var err error
+			if test.add {
+				rule := ruleHostFoo()
+				_, err = ingressService.AddRule(context.TODO(), &rule, "", false)
+			} else {
+				_, _, err = ingressService.DeleteRule(context.TODO(), RuleSelector{ServiceName: "service-foo"})
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedAction, action)
+			for _, line := range test.expectedDiff {
+				assert.Contains(t, diff.String(), line+"\n")
+			}
+		})
+	}
+}
+
+func TestIngressService_RetryOnConflict(t *testing.T) {
+	tests := []struct {
+		name          string
+		conflicts     int
+		retries       int
+		add           bool
+		expectedGets  int
+		expectedError bool
+	}{
+		{
+			name:         "add rule without conflict",
+			conflicts:    0,
+			retries:      3,
+			add:          true,
+			expectedGets: 1,
+		},
+		{
+			name:         "add rule is retried on conflict",
+			conflicts:    2,
+			retries:      3,
+			add:          true,
+			expectedGets: 3,
+		},
+		{
+			name:          "add rule fails when retries are exhausted",
+			conflicts:     4,
+			retries:       3,
+			add:           true,
+			expectedGets:  4,
+			expectedError: true,
+		},
+		{
+			name:          "add rule is not retried without retries",
+			conflicts:     1,
+			retries:       0,
+			add:           true,
+			expectedGets:  1,
+			expectedError: true,
+		},
+		{
+			name:         "delete rule is retried on conflict",
+			conflicts:    2,
+			retries:      3,
+			expectedGets: 3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gets := 0
+			updates := 0
+
+			f := clienttesting.Fake{}
+			f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				gets++
+				// every attempt has to start from a fresh copy of the live ingress
+				return true, &networking.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+					Spec: networking.IngressSpec{
+						Rules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+					},
+				}, nil
+			})
+			f.AddReactor("update", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+				updates++
+				if updates <= test.conflicts {
+					return true, nil, errors2.NewConflict(action.GetResource().GroupResource(), "foo", errors.New("object has been modified"))
+				}
+				spec := action.(clienttesting.UpdateAction).GetObject().(*networking.Ingress).Spec
+				if test.add {
+					assert.Equal(t, []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar(), ruleHostBaz()}, spec.Rules)
+				} else {
+					assert.Equal(t, []networking.IngressRule{ruleHostFoo2(), ruleHostBar()}, spec.Rules)
+				}
+				return true, action.(clienttesting.UpdateAction).GetObject(), nil
+			})
+
+			ingressService := IngressService{
+				kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+				ingressName: "foo",
+			}
+			ingressService.SetRetry(test.retries, time.Minute)
+
+			var err error
+			if test.add {
+				rule := ruleHostBaz()
+				_, err = ingressService.AddRule(context.TODO(), &rule, "", false)
+			} else {
+				_, _, err = ingressService.DeleteRule(context.TODO(), RuleSelector{ServiceName: "service-foo"})
+			}
+			if test.expectedError {
+				assert.True(t, errors2.IsConflict(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedGets, gets)
+		})
+	}
+}
+
+func TestIngressService_RetryTimeout(t *testing.T) {
+	f := clienttesting.Fake{}
+	f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec: networking.IngressSpec{
+				Rules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			},
+		}, nil
+	})
+	f.AddReactor("update", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, errors2.NewConflict(action.GetResource().GroupResource(), "foo", errors.New("object has been modified"))
+	})
+
+	ingressService := IngressService{
+		kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+		ingressName: "foo",
+	}
+	ingressService.SetRetry(100, 200*time.Millisecond)
+
+	start := time.Now()
+	rule := ruleHostBaz()
+	_, err := ingressService.AddRule(context.TODO(), &rule, "", false)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.True(t, errors2.IsConflict(err))
+	assert.Contains(t, err.Error(), "retry timeout of 200ms exceeded")
+}
+
+func ruleHostFooServiceBaz() networking.IngressRule {
+	rule := ruleHostFoo()
+	rule.HTTP.Paths[0].Backend.Service.Name = "service-baz"
+
+	return rule
+}
+
+func ruleHostFooServiceBazAndFoo2() networking.IngressRule {
+	rule := ruleHostFooTwoRules()
+	rule.HTTP.Paths[0].Backend.Service.Name = "service-baz"
+
+	return rule
+}
+
+func ruleHostFooExactServiceBaz() networking.IngressRule {
+	pathType := networking.PathTypeExact
+	rule := ruleHostFooServiceBaz()
+	rule.HTTP.Paths[0].PathType = &pathType
+
+	return rule
+}
+
+func ruleHostFooAndExactServiceBaz() networking.IngressRule {
+	rule := ruleHostFoo()
+	rule.HTTP.Paths = append(rule.HTTP.Paths, ruleHostFooExactServiceBaz().HTTP.Paths[0])
+
+	return rule
+}
+
+func ruleHostBaz() networking.IngressRule {
+	rule := ruleHostBar()
+	rule.Host = "baz.com"
+	rule.HTTP.Paths[0].Backend.Service.Name = "service-baz"
+
+	return rule
+}
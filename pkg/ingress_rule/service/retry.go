@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between two attempts, so the timeout and not the backoff bounds the retries.
+const maxRetryDelay = time.Second
+
+// updateSettings configure how the services persist their modifications, they are shared by the ingress and the HTTPRoute service.
+type updateSettings struct {
+	dryRun  DryRunStrategy
+	diffOut io.Writer
+	backoff *wait.Backoff
+	timeout time.Duration
+}
+
+// SetRetry configures how often a conflicting read-modify-write of the resource is retried.
+// A timeout of zero disables the timeout.
+func (u *updateSettings) SetRetry(retries int, timeout time.Duration) {
+	backoff := retry.DefaultBackoff
+	backoff.Steps = retries + 1
+	u.backoff = &backoff
+	u.timeout = timeout
+}
+
+// retryOnConflict executes fn until it succeeds, fails with an error other than a conflict or the retries are exhausted.
+// fn has to read the current state of the resource on every attempt so the modification is recomputed.
+// A concurrently created resource is treated as conflict as well.
+// When the timeout expires the last conflict is returned wrapped with the timeout.
+func (u *updateSettings) retryOnConflict(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := retry.DefaultBackoff
+	if u.backoff != nil {
+		backoff = *u.backoff
+	}
+
+	if u.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.timeout)
+		defer cancel()
+	}
+
+	var conflict error
+	for {
+		if err := ctx.Err(); err != nil {
+			return u.timeoutError(err, conflict)
+		}
+
+		err := fn(ctx)
+		if !isConflict(err) {
+			if conflict != nil && ctx.Err() != nil {
+				return u.timeoutError(ctx.Err(), conflict)
+			}
+			return err
+		}
+		conflict = err
+
+		if backoff.Steps <= 1 {
+			return conflict
+		}
+		delay := backoff.Step()
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		if backoff.Duration > maxRetryDelay {
+			backoff.Duration = maxRetryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return u.timeoutError(ctx.Err(), conflict)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// timeoutError returns the last conflict instead of the context error, so callers can still tell what failed.
+func (u *updateSettings) timeoutError(err error, conflict error) error {
+	if conflict == nil {
+		return err
+	}
+	return fmt.Errorf("retry timeout of %s exceeded: %w", u.timeout, conflict)
+}
+
+func isConflict(err error) bool {
+	return apierror.IsConflict(err) || apierror.IsAlreadyExists(err)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	"strconv"
+)
+
+// IngressRuleRow is a single flattened backend rule of an ingress.
+type IngressRuleRow struct {
+	Namespace    string `json:"namespace"`
+	Ingress      string `json:"ingress"`
+	IngressClass string `json:"ingressClass,omitempty"`
+	Host         string `json:"host"`
+	Path         string `json:"path"`
+	PathType     string `json:"pathType"`
+	Service      string `json:"service,omitempty"`
+	Port         string `json:"port,omitempty"`
+	Resource     string `json:"resource,omitempty"` // resource backend formatted as "<kind>[.<api group>]/<name>", empty for service backends
+	TlsSecret    string `json:"tlsSecret,omitempty"`
+}
+
+// FlattenIngressRules flattens the rules, paths and tls configuration of an ingress into one row per backend path.
+func FlattenIngressRules(ingress *networking.Ingress) []IngressRuleRow {
+	var rows []IngressRuleRow
+
+	ingressClass := ""
+	if ingress.Spec.IngressClassName != nil {
+		ingressClass = *ingress.Spec.IngressClassName
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			row := IngressRuleRow{
+				Namespace:    ingress.Namespace,
+				Ingress:      ingress.Name,
+				IngressClass: ingressClass,
+				Host:         rule.Host,
+				Path:         p.Path,
+				TlsSecret:    tlsSecretForHost(ingress, rule.Host),
+			}
+			if p.PathType != nil {
+				row.PathType = string(*p.PathType)
+			}
+			if p.Backend.Service != nil {
+				row.Service = p.Backend.Service.Name
+				row.Port = FormatServicePort(p.Backend.Service.Port)
+			}
+			if p.Backend.Resource != nil {
+				row.Resource = FormatResource(*p.Backend.Resource)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// tlsSecretForHost returns the name of the tls secret configured for the host or an empty string if there is none.
+func tlsSecretForHost(ingress *networking.Ingress, host string) string {
+	if host == "" {
+		return ""
+	}
+	for _, tlsEntry := range ingress.Spec.TLS {
+		for _, tlsHost := range tlsEntry.Hosts {
+			if tlsHost == host {
+				return tlsEntry.SecretName
+			}
+		}
+	}
+	return ""
+}
+
+// FormatServicePort returns a printable representation of a backend service port.
+func FormatServicePort(port networking.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.Number))
+}
+
+// FormatResource returns a printable representation of a resource backend, the api group is omitted for the core api group.
+func FormatResource(resource core.TypedLocalObjectReference) string {
+	if resource.APIGroup != nil && *resource.APIGroup != "" {
+		return fmt.Sprintf("%s.%s/%s", resource.Kind, *resource.APIGroup, resource.Name)
+	}
+	return fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
+}
+
+// FormatBackend returns a printable representation of an ingress backend.
+func FormatBackend(backend networking.IngressBackend) string {
+	if backend.Service != nil {
+		return fmt.Sprintf("service '%s' (port: '%s')", backend.Service.Name, FormatServicePort(backend.Service.Port))
+	}
+	if backend.Resource != nil {
+		return fmt.Sprintf("resource '%s'", FormatResource(*backend.Resource))
+	}
+	return "<none>"
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestFlattenIngressRules(t *testing.T) {
+	ingressClass := "nginx"
+	namedPortRule := ruleHostFoo()
+	namedPortRule.HTTP.Paths[0].Backend.Service.Port = networking.ServiceBackendPort{Name: "http"}
+
+	tests := []struct {
+		name         string
+		rules        []networking.IngressRule
+		tls          []networking.IngressTLS
+		expectedRows []IngressRuleRow
+	}{
+		{
+			name:         "ingress without rules",
+			expectedRows: nil,
+		},
+		{
+			name:  "rules with multiple paths",
+			rules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			expectedRows: []IngressRuleRow{
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "foo.com", Path: "/", PathType: "Prefix", Service: "service-foo", Port: "80"},
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "foo.com", Path: "/2", PathType: "Prefix", Service: "service-foo-2", Port: "80"},
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "bar.com", Path: "/", PathType: "Prefix", Service: "service-bar", Port: "80"},
+			},
+		},
+		{
+			name:  "rule with named port and tls secret",
+			rules: []networking.IngressRule{namedPortRule, ruleHostBar()},
+			tls: []networking.IngressTLS{{
+				Hosts:      []string{"foo.com"},
+				SecretName: "my-secret",
+			}},
+			expectedRows: []IngressRuleRow{
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "foo.com", Path: "/", PathType: "Prefix", Service: "service-foo", Port: "http", TlsSecret: "my-secret"},
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "bar.com", Path: "/", PathType: "Prefix", Service: "service-bar", Port: "80"},
+			},
+		},
+		{
+			name: "rule with resource backend",
+			rules: []networking.IngressRule{
+				*CreateIngressRuleWithBackend("foo.com", "/static", networking.PathTypePrefix, CreateResourceBackend("k8s.example.com", "StorageBucket", "static-assets")),
+				*CreateIngressRuleWithBackend("bar.com", "/", networking.PathTypeExact, CreateResourceBackend("", "ConfigMap", "static-assets")),
+			},
+			expectedRows: []IngressRuleRow{
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "foo.com", Path: "/static", PathType: "Prefix", Resource: "StorageBucket.k8s.example.com/static-assets"},
+				{Namespace: "default", Ingress: "foo", IngressClass: ingressClass, Host: "bar.com", Path: "/", PathType: "Exact", Resource: "ConfigMap/static-assets"},
+			},
+		},
+		{
+			name:         "rule without http paths",
+			rules:        []networking.IngressRule{{Host: "foo.com"}},
+			expectedRows: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+				},
+				Spec: networking.IngressSpec{
+					IngressClassName: &ingressClass,
+					Rules:            test.rules,
+					TLS:              test.tls,
+				},
+			}
+
+			assert.Equal(t, test.expectedRows, FlattenIngressRules(ingress))
+		})
+	}
+}
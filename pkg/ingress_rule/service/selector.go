@@ -0,0 +1,61 @@
+package service
+
+import (
+	networking "k8s.io/api/networking/v1"
+)
+
+// RuleSelector selects paths of an ingress.
+// All configured criteria have to match, criteria which are not configured match every path.
+type RuleSelector struct {
+	// Host selects paths of the rule for the host, nil matches every host.
+	// An empty host selects the rule without host.
+	Host *string
+	// Path selects paths by their path, an empty path matches every path.
+	Path string
+	// PathType selects paths by their path type, nil matches every path type.
+	PathType *networking.PathType
+	// ServiceName selects paths by the name of their backend service, an empty name matches every backend.
+	ServiceName string
+	// ServicePort selects paths by the port of their backend service either by number or by name, an empty port matches every port.
+	ServicePort networking.ServiceBackendPort
+}
+
+// IsEmpty checks if no criteria is configured, an empty selector would match every path of the ingress.
+func (s RuleSelector) IsEmpty() bool {
+	return s.Host == nil && s.Path == "" && s.PathType == nil && s.ServiceName == "" &&
+		s.ServicePort == networking.ServiceBackendPort{}
+}
+
+// matches checks if the path of the rule for the host matches all criteria of the selector.
+func (s RuleSelector) matches(host string, path networking.HTTPIngressPath) bool {
+	if s.Host != nil && *s.Host != host {
+		return false
+	}
+	if s.Path != "" && s.Path != path.Path {
+		return false
+	}
+	if s.PathType != nil && (path.PathType == nil || *s.PathType != *path.PathType) {
+		return false
+	}
+	if s.ServiceName != "" || s.ServicePort != (networking.ServiceBackendPort{}) {
+		if path.Backend.Service == nil {
+			return false
+		}
+		if s.ServiceName != "" && s.ServiceName != path.Backend.Service.Name {
+			return false
+		}
+		if !servicePortMatches(path.Backend.Service.Port, s.ServicePort) {
+			return false
+		}
+	}
+	return true
+}
+
+// servicePortMatches checks if a backend port matches the given port either by name or by number.
+// An empty port matches every backend port.
+func servicePortMatches(backendPort networking.ServiceBackendPort, port networking.ServiceBackendPort) bool {
+	if port.Name != "" {
+		return backendPort.Name == port.Name
+	}
+	return port.Number == 0 || backendPort.Number == port.Number
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"math/big"
+	"strings"
+	"time"
+)
+
+type KeyType string
+
+const (
+	KeyTypeECDSAP256 KeyType = "ecdsa-p256"
+	KeyTypeECDSAP384 KeyType = "ecdsa-p384"
+	KeyTypeRSA2048   KeyType = "rsa-2048"
+	KeyTypeRSA4096   KeyType = "rsa-4096"
+	KeyTypeEd25519   KeyType = "ed25519"
+)
+
+// KeyTypes are the supported key types of self-signed certificates.
+var KeyTypes = []KeyType{KeyTypeECDSAP256, KeyTypeECDSAP384, KeyTypeRSA2048, KeyTypeRSA4096, KeyTypeEd25519}
+
+// SelfSignedSecretName returns the name of the secret containing the self-signed certificate for the host.
+func SelfSignedSecretName(host string) string {
+	return strings.ToLower(hostSlug(host)) + "-self-signed-tls"
+}
+
+// SelfSignedCertificateReusable checks that the certificate of the secret covers the hosts and is not due for renewal without accessing the cluster.
+// The certificate is renewed once less than a third of the requested validity remains, short validities are not renewed on every use.
+func SelfSignedCertificateReusable(secret *core.Secret, hosts []string, validity time.Duration, now time.Time) bool {
+	cert, err := ParseTlsSecret(secret)
+	if err != nil || now.Before(cert.NotBefore) {
+		return false
+	}
+	for _, host := range hosts {
+		if !CertificateCoversHost(cert, host) {
+			return false
+		}
+	}
+	return cert.NotAfter.Sub(now) >= validity/3
+}
+
+// GenerateSelfSignedCertificate generates a key of the key type and a self-signed server certificate for the hosts valid from now on.
+// Returns the PEM encoded certificate and PKCS #8 private key
+func GenerateSelfSignedCertificate(hosts []string, validity time.Duration, keyType KeyType, now time.Time) (certPEM []byte, keyPEM []byte, err error) {
+	key, err := generateKey(keyType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if keyType == KeyTypeRSA2048 || keyType == KeyTypeRSA4096 {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: hosts[0]},
+		DNSNames:              hosts,
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM, nil
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("key type '%s' is not supported", keyType)
+	}
+}
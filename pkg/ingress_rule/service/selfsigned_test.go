@@ -0,0 +1,49 @@
+package service
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, keyType := range KeyTypes {
+		t.Run(string(keyType), func(t *testing.T) {
+			certPEM, keyPEM, err := GenerateSelfSignedCertificate([]string{"*.example.com"}, 30*24*time.Hour, keyType, now)
+			assert.NoError(t, err)
+
+			_, err = tls.X509KeyPair(certPEM, keyPEM)
+			assert.NoError(t, err)
+
+			warnings, err := ValidateTlsSecret(NewTlsSecret("my-secret", certPEM, keyPEM), []string{"foo.example.com"}, now)
+			assert.NoError(t, err)
+			assert.Empty(t, warnings)
+		})
+	}
+
+	_, _, err := GenerateSelfSignedCertificate([]string{"foo.com"}, time.Hour, "dsa", now)
+	assert.Error(t, err)
+}
+
+func TestSelfSignedCertificateReusable(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	validity := 30 * 24 * time.Hour
+	certPEM, keyPEM, err := GenerateSelfSignedCertificate([]string{"foo.com"}, validity, KeyTypeECDSAP256, now)
+	assert.NoError(t, err)
+	secret := NewTlsSecret("foo-com-self-signed-tls", certPEM, keyPEM)
+
+	// validities shorter than CertificateExpiryWarning are reused
+	assert.True(t, SelfSignedCertificateReusable(secret, []string{"foo.com"}, validity, now))
+	assert.True(t, SelfSignedCertificateReusable(secret, []string{"foo.com"}, validity, now.Add(20*24*time.Hour)))
+	assert.False(t, SelfSignedCertificateReusable(secret, []string{"foo.com"}, validity, now.Add(21*24*time.Hour)))
+	assert.False(t, SelfSignedCertificateReusable(secret, []string{"foo.com"}, 100*24*time.Hour, now))
+	assert.False(t, SelfSignedCertificateReusable(secret, []string{"bar.com"}, validity, now))
+	assert.False(t, SelfSignedCertificateReusable(NewTlsSecret("invalid", []byte("invalid"), nil), []string{"foo.com"}, validity, now))
+}
+
+func TestSelfSignedSecretName(t *testing.T) {
+	assert.Equal(t, "foo-example-com-self-signed-tls", SelfSignedSecretName("Foo.example.com"))
+	assert.Equal(t, "wildcard-example-com-self-signed-tls", SelfSignedSecretName("*.example.com"))
+}
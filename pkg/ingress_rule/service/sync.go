@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// ManagedRulesAnnotation lists the rules of an ingress which have been synced by this tool.
+const ManagedRulesAnnotation = "ingress-rule.pragaonj.github.io/managed-rules"
+
+type PruneMode int
+
+const (
+	// PruneNone keeps all rules which are not part of the desired rules.
+	PruneNone PruneMode = iota
+	// PruneManaged removes rules which are not part of the desired rules but have been synced before.
+	PruneManaged
+	// PruneAll removes all rules which are not part of the desired rules.
+	PruneAll
+)
+
+// DesiredRule describes a backend rule which should exist in an ingress.
+type DesiredRule struct {
+	Host        string
+	Path        string
+	PathType    networking.PathType
+	ServiceName string
+	ServicePort networking.ServiceBackendPort
+	TlsSecret   string
+}
+
+func (d DesiredRule) key() RuleKey {
+	return RuleKey{Host: d.Host, Path: d.Path, PathType: d.PathType}
+}
+
+// RuleKey identifies a path of an ingress by host, path and path type.
+type RuleKey struct {
+	Host     string              `json:"host"`
+	Path     string              `json:"path"`
+	PathType networking.PathType `json:"pathType"`
+}
+
+func (k RuleKey) String() string {
+	return fmt.Sprintf("host '%s' with path '%s' (path type: '%s')", k.Host, k.Path, k.PathType)
+}
+
+// SyncResult reports the changes made to an ingress by a sync.
+type SyncResult struct {
+	Created bool
+	Deleted bool
+	Added   []RuleKey
+	Updated []RuleKey
+	Removed []RuleKey
+	// TlsChanges are the hosts added to or removed from the tls configuration
+	TlsChanges []TlsChange
+}
+
+// Sync reconciles the ingress with the desired rules in a single create, update or delete.
+// Rules which are not desired are removed according to the prune mode.
+// The ingress is created if it does not exist and deleted if there are no rules and no default backend left.
+// Conflicting concurrent modifications of the ingress are retried.
+func (i *IngressService) Sync(ctx context.Context, desired []DesiredRule, prune PruneMode) (result SyncResult, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if apierror.IsNotFound(err) {
+			if len(desired) == 0 {
+				result = SyncResult{}
+				return nil
+			}
+			ingress = NewIngress(i.ingressName, i.ingressClassName)
+			result, err = applySync(ingress, desired, prune)
+			if err != nil {
+				return err
+			}
+			result.Created = true
+			return i.create(ctx, ingress)
+		} else if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		result, err = applySync(ingress, desired, prune)
+		if err != nil {
+			return err
+		}
+		if len(ingress.Spec.Rules) == 0 && ingress.Spec.DefaultBackend == nil {
+			// delete ingress when the last rule is removed
+			result.Deleted = true
+			return i.delete(ctx, live)
+		}
+		if equality.Semantic.DeepEqual(live, ingress) {
+			return nil
+		}
+
+		return i.update(ctx, live, ingress)
+	})
+
+	return result, err
+}
+
+// applySync adds or updates the desired rules and removes rules according to the prune mode.
+// Tls hosts which are not desired with a secret are removed according to the prune mode as well.
+// The desired rules are recorded in the ManagedRulesAnnotation.
+func applySync(ingress *networking.Ingress, desired []DesiredRule, prune PruneMode) (SyncResult, error) {
+	result := SyncResult{}
+
+	desiredKeys := map[RuleKey]bool{}
+	for _, rule := range desired {
+		desiredKeys[rule.key()] = true
+	}
+
+	managedKeys, err := managedRules(ingress)
+	if err != nil {
+		return result, err
+	}
+
+	// remove rules which are no longer desired
+	var newRules []networking.IngressRule
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			newRules = append(newRules, rule)
+			continue
+		}
+		var newPaths []networking.HTTPIngressPath
+		for _, p := range rule.HTTP.Paths {
+			key := pathKey(rule.Host, p)
+			if !desiredKeys[key] && (prune == PruneAll || (prune == PruneManaged && managedKeys[key])) {
+				result.Removed = append(result.Removed, key)
+				continue
+			}
+			newPaths = append(newPaths, p)
+		}
+		if len(newPaths) > 0 {
+			rule.HTTP.Paths = newPaths
+			newRules = append(newRules, rule)
+		}
+	}
+	ingress.Spec.Rules = newRules
+
+	// add or update the desired rules
+	tlsSecrets := map[string]string{}
+	for _, d := range desired {
+		ingressRule := CreateIngressRule(d.Host, d.Path, d.PathType, d.ServiceName, d.ServicePort)
+		existing := findPath(ingress, d.key())
+		if existing == nil {
+			if err := ApplyAddRule(ingress, ingressRule, "", false); err != nil {
+				return result, err
+			}
+			result.Added = append(result.Added, d.key())
+		} else if !equality.Semantic.DeepEqual(existing.Backend, ingressRule.HTTP.Paths[0].Backend) {
+			existing.Backend = ingressRule.HTTP.Paths[0].Backend
+			result.Updated = append(result.Updated, d.key())
+		}
+
+		if d.TlsSecret != "" && d.Host != "" {
+			tlsSecrets[d.Host] = d.TlsSecret
+		}
+	}
+
+	// remove tls hosts which are no longer desired with a secret, an empty secret removes the host
+	managedHosts := map[string]bool{}
+	for key := range managedKeys {
+		managedHosts[key.Host] = true
+	}
+	for _, tlsEntry := range ingress.Spec.TLS {
+		for _, host := range tlsEntry.Hosts {
+			if _, ok := tlsSecrets[host]; !ok && (prune == PruneAll || (prune == PruneManaged && managedHosts[host])) {
+				tlsSecrets[host] = ""
+			}
+		}
+	}
+
+	if len(result.Removed) > 0 || len(tlsSecrets) > 0 {
+		result.TlsChanges = reconcileTls(ingress, tlsSecrets)
+	}
+
+	return result, setManagedRules(ingress, desiredKeys)
+}
+
+// findPath returns the path of the ingress identified by the key or nil if there is no such path.
+func findPath(ingress *networking.Ingress, key RuleKey) *networking.HTTPIngressPath {
+	for i1, rule := range ingress.Spec.Rules {
+		if rule.Host != key.Host || rule.HTTP == nil {
+			continue
+		}
+		for i2, p := range rule.HTTP.Paths {
+			if pathKey(rule.Host, p) == key {
+				return &ingress.Spec.Rules[i1].HTTP.Paths[i2]
+			}
+		}
+	}
+	return nil
+}
+
+func pathKey(host string, path networking.HTTPIngressPath) RuleKey {
+	key := RuleKey{Host: host, Path: path.Path}
+	if path.PathType != nil {
+		key.PathType = *path.PathType
+	}
+	return key
+}
+
+// managedRules returns the keys of the rules recorded in the ManagedRulesAnnotation.
+func managedRules(ingress *networking.Ingress) (map[RuleKey]bool, error) {
+	keys := map[RuleKey]bool{}
+
+	value, ok := ingress.Annotations[ManagedRulesAnnotation]
+	if !ok {
+		return keys, nil
+	}
+
+	var managed []RuleKey
+	if err := json.Unmarshal([]byte(value), &managed); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation '%s': %w", ManagedRulesAnnotation, err)
+	}
+	for _, key := range managed {
+		keys[key] = true
+	}
+	return keys, nil
+}
+
+// setManagedRules records the keys in the ManagedRulesAnnotation in a deterministic order.
+func setManagedRules(ingress *networking.Ingress, keys map[RuleKey]bool) error {
+	managed := make([]RuleKey, 0, len(keys))
+	for key := range keys {
+		managed = append(managed, key)
+	}
+	sort.Slice(managed, func(a, b int) bool {
+		return managed[a].String() < managed[b].String()
+	})
+
+	value, err := json.Marshal(managed)
+	if err != nil {
+		return err
+	}
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[ManagedRulesAnnotation] = string(value)
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestApplySync(t *testing.T) {
+	desiredFoo := DesiredRule{Host: "foo.com", Path: "/", PathType: networking.PathTypePrefix, ServiceName: "service-foo", ServicePort: networking.ServiceBackendPort{Number: 80}}
+	desiredFoo2 := DesiredRule{Host: "foo.com", Path: "/2", PathType: networking.PathTypePrefix, ServiceName: "service-foo-2", ServicePort: networking.ServiceBackendPort{Number: 80}}
+	desiredBar := DesiredRule{Host: "bar.com", Path: "/", PathType: networking.PathTypePrefix, ServiceName: "service-bar", ServicePort: networking.ServiceBackendPort{Number: 80}}
+	desiredFooTls := DesiredRule{Host: "foo.com", Path: "/", PathType: networking.PathTypePrefix, ServiceName: "service-foo", ServicePort: networking.ServiceBackendPort{Number: 80}, TlsSecret: "my-secret"}
+	desiredFooServiceBaz := DesiredRule{Host: "foo.com", Path: "/", PathType: networking.PathTypePrefix, ServiceName: "service-baz", ServicePort: networking.ServiceBackendPort{Number: 80}}
+
+	tests := []struct {
+		name               string
+		existingRules      []networking.IngressRule
+		existingTls        []networking.IngressTLS
+		managedAnnotation  string
+		desired            []DesiredRule
+		prune              PruneMode
+		expectedRules      []networking.IngressRule
+		expectedTls        []networking.IngressTLS
+		expectedResult     SyncResult
+		expectedAnnotation string
+	}{
+		{
+			name:               "add desired rules to empty ingress",
+			desired:            []DesiredRule{desiredFoo, desiredFoo2},
+			prune:              PruneAll,
+			expectedRules:      []networking.IngressRule{ruleHostFooTwoRules()},
+			expectedResult:     SyncResult{Added: []RuleKey{desiredFoo.key(), desiredFoo2.key()}},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"},{"host":"foo.com","path":"/2","pathType":"Prefix"}]`,
+		},
+		{
+			name:               "keep existing desired rules",
+			existingRules:      []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			desired:            []DesiredRule{desiredFoo, desiredBar},
+			prune:              PruneAll,
+			expectedRules:      []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedResult:     SyncResult{},
+			expectedAnnotation: `[{"host":"bar.com","path":"/","pathType":"Prefix"},{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:               "update backend of existing rule",
+			existingRules:      []networking.IngressRule{ruleHostFoo()},
+			desired:            []DesiredRule{desiredFooServiceBaz},
+			prune:              PruneAll,
+			expectedRules:      []networking.IngressRule{ruleHostFooServiceBaz()},
+			expectedResult:     SyncResult{Updated: []RuleKey{desiredFoo.key()}},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:          "prune all rules which are not desired",
+			existingRules: []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			existingTls:   []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			desired:       []DesiredRule{desiredFooTls},
+			prune:         PruneAll,
+			expectedRules: []networking.IngressRule{ruleHostFoo()},
+			expectedTls:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedResult: SyncResult{
+				Removed:    []RuleKey{desiredFoo2.key(), desiredBar.key()},
+				TlsChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret", Removed: true}},
+			},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:               "prune only managed rules",
+			existingRules:      []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			managedAnnotation:  `[{"host":"foo.com","path":"/","pathType":"Prefix"},{"host":"foo.com","path":"/2","pathType":"Prefix"}]`,
+			desired:            []DesiredRule{desiredFoo},
+			prune:              PruneManaged,
+			expectedRules:      []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedResult:     SyncResult{Removed: []RuleKey{desiredFoo2.key()}},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:               "do not prune rules without prune mode",
+			existingRules:      []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			desired:            []DesiredRule{desiredFoo},
+			prune:              PruneNone,
+			expectedRules:      []networking.IngressRule{ruleHostFooTwoRules(), ruleHostBar()},
+			expectedResult:     SyncResult{},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:          "move host to desired tls secret",
+			existingRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			existingTls:   []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-old-secret"}},
+			desired:       []DesiredRule{desiredFooTls, desiredBar},
+			prune:         PruneNone,
+			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"bar.com"}, SecretName: "my-old-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "my-secret"},
+			},
+			expectedResult: SyncResult{TlsChanges: []TlsChange{
+				{Host: "foo.com", Secret: "my-old-secret", Removed: true},
+				{Host: "foo.com", Secret: "my-secret"},
+			}},
+			expectedAnnotation: `[{"host":"bar.com","path":"/","pathType":"Prefix"},{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:          "prune tls of desired host without tls secret",
+			existingRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			existingTls:   []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			desired:       []DesiredRule{desiredFooTls, desiredBar},
+			prune:         PruneAll,
+			expectedRules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedTls:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedResult: SyncResult{
+				TlsChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret", Removed: true}},
+			},
+			expectedAnnotation: `[{"host":"bar.com","path":"/","pathType":"Prefix"},{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:              "prune only tls of managed hosts without tls secret",
+			existingRules:     []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			existingTls:       []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			managedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+			desired:           []DesiredRule{desiredFoo},
+			prune:             PruneManaged,
+			expectedRules:     []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+			expectedTls:       []networking.IngressTLS{{Hosts: []string{"bar.com"}, SecretName: "my-secret"}},
+			expectedResult: SyncResult{
+				TlsChanges: []TlsChange{{Host: "foo.com", Secret: "my-secret", Removed: true}},
+			},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+		{
+			name:               "keep tls of desired host without tls secret without prune mode",
+			existingRules:      []networking.IngressRule{ruleHostFoo()},
+			existingTls:        []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			desired:            []DesiredRule{desiredFoo},
+			prune:              PruneNone,
+			expectedRules:      []networking.IngressRule{ruleHostFoo()},
+			expectedTls:        []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedResult:     SyncResult{},
+			expectedAnnotation: `[{"host":"foo.com","path":"/","pathType":"Prefix"}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec: networking.IngressSpec{
+					Rules: test.existingRules,
+					TLS:   test.existingTls,
+				},
+			}
+			if test.managedAnnotation != "" {
+				ingress.Annotations = map[string]string{ManagedRulesAnnotation: test.managedAnnotation}
+			}
+
+			result, err := applySync(ingress, test.desired, test.prune)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedResult, result)
+			assert.Equal(t, test.expectedRules, ingress.Spec.Rules)
+			assert.Equal(t, test.expectedTls, ingress.Spec.TLS)
+			assert.Equal(t, test.expectedAnnotation, ingress.Annotations[ManagedRulesAnnotation])
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+)
+
+// TlsChange is a host added to or removed from the tls configuration of a secret.
+// An empty host refers to a tls entry without hosts.
+type TlsChange struct {
+	Host    string
+	Secret  string
+	Removed bool
+}
+
+func (c TlsChange) String() string {
+	if c.Host == "" && c.Removed {
+		return fmt.Sprintf("removed tls secret '%s' of the default certificate", c.Secret)
+	}
+	if c.Host == "" {
+		return fmt.Sprintf("added tls secret '%s' as default certificate", c.Secret)
+	}
+	if c.Removed {
+		return fmt.Sprintf("removed host '%s' from tls secret '%s'", c.Host, c.Secret)
+	}
+	return fmt.Sprintf("added host '%s' to tls secret '%s'", c.Host, c.Secret)
+}
+
+// AddTls configures the tls secret for the hosts of the ingress.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns the changes of the tls configuration and an error
+func (i *IngressService) AddTls(ctx context.Context, hosts []string, secret string) ([]TlsChange, error) {
+	return i.updateTls(ctx, func(ingress *networking.Ingress) ([]TlsChange, error) {
+		return ApplyAddTls(ingress, hosts, secret)
+	})
+}
+
+// CheckAddTls applies the tls secret to the current ingress without updating it.
+// It is used to detect failures of AddTls before the tls secret is written.
+// Returns the error AddTls would return
+func (i *IngressService) CheckAddTls(ctx context.Context, hosts []string, secret string) error {
+	ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = ApplyAddTls(ingress, hosts, secret)
+	return err
+}
+
+// ApplyAddTls configures the tls secret for the hosts without accessing the cluster.
+// Every host has to be used by a rule, a wildcard host has to cover the host of a rule.
+// Returns ErrTlsConfigurationAlreadyExists if a host already uses a different secret.
+func ApplyAddTls(ingress *networking.Ingress, hosts []string, secret string) ([]TlsChange, error) {
+	secrets := map[string]string{}
+	for _, host := range hosts {
+		if !tlsHostUsed(host, ruleHosts(ingress)) {
+			return nil, fmt.Errorf("%w: '%s'", ErrTlsHostNotFound, host)
+		}
+		if existing, ok := tlsSecretOfHost(ingress, host); ok && existing != secret {
+			return nil, fmt.Errorf("%w: host '%s' uses tls secret '%s'", ErrTlsConfigurationAlreadyExists, host, existing)
+		}
+		secrets[host] = secret
+	}
+
+	return reconcileTls(ingress, secrets), nil
+}
+
+// RemoveTls removes the hosts from the tls configuration of the ingress, an empty secret removes the hosts from every secret.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns the changes of the tls configuration and an error
+func (i *IngressService) RemoveTls(ctx context.Context, hosts []string, secret string) ([]TlsChange, error) {
+	return i.updateTls(ctx, func(ingress *networking.Ingress) ([]TlsChange, error) {
+		return ApplyRemoveTls(ingress, hosts, secret)
+	})
+}
+
+// ApplyRemoveTls removes the hosts from the tls configuration without accessing the cluster.
+// Returns ErrTlsConfigurationNotFound if a host has no tls configuration.
+func ApplyRemoveTls(ingress *networking.Ingress, hosts []string, secret string) ([]TlsChange, error) {
+	remove := map[string]bool{}
+	for _, host := range hosts {
+		_, found := tlsSecretOfHost(ingress, host)
+		if secret != "" {
+			found = tlsEntryContains(ingress, host, secret)
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: '%s'", ErrTlsConfigurationNotFound, host)
+		}
+		remove[host] = true
+	}
+
+	before := ingress.Spec.TLS
+	var newTlsConfig []networking.IngressTLS
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if len(tlsEntry.Hosts) == 0 || (secret != "" && tlsEntry.SecretName != secret) {
+			newTlsConfig = append(newTlsConfig, tlsEntry)
+			continue
+		}
+		var entryHosts []string
+		for _, host := range tlsEntry.Hosts {
+			if !remove[host] {
+				entryHosts = append(entryHosts, host)
+			}
+		}
+		if len(entryHosts) > 0 {
+			tlsEntry.Hosts = entryHosts
+			newTlsConfig = append(newTlsConfig, tlsEntry)
+		}
+	}
+
+	ingress.Spec.TLS = newTlsConfig
+	return tlsChanges(before, newTlsConfig), nil
+}
+
+// RotateTls moves the hosts of the ingress to another tls secret.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns the changes of the tls configuration and an error
+func (i *IngressService) RotateTls(ctx context.Context, hosts []string, secret string) ([]TlsChange, error) {
+	return i.updateTls(ctx, func(ingress *networking.Ingress) ([]TlsChange, error) {
+		return ApplyRotateTls(ingress, hosts, secret)
+	})
+}
+
+// ApplyRotateTls moves the hosts to the tls secret without accessing the cluster, entries are split or merged as needed.
+// Returns ErrTlsConfigurationNotFound if a host has no tls configuration.
+func ApplyRotateTls(ingress *networking.Ingress, hosts []string, secret string) ([]TlsChange, error) {
+	secrets := map[string]string{}
+	for _, host := range hosts {
+		if _, ok := tlsSecretOfHost(ingress, host); !ok {
+			return nil, fmt.Errorf("%w: '%s'", ErrTlsConfigurationNotFound, host)
+		}
+		secrets[host] = secret
+	}
+
+	return reconcileTls(ingress, secrets), nil
+}
+
+// RotateTlsSecret replaces the tls secret in all tls entries of the ingress referencing it.
+// Conflicting concurrent modifications of the ingress are retried.
+// Returns the changes of the tls configuration and an error
+func (i *IngressService) RotateTlsSecret(ctx context.Context, from string, to string) ([]TlsChange, error) {
+	return i.updateTls(ctx, func(ingress *networking.Ingress) ([]TlsChange, error) {
+		return ApplyRotateTlsSecret(ingress, from, to), nil
+	})
+}
+
+// ApplyRotateTlsSecret replaces the tls secret in all tls entries referencing it without accessing the cluster.
+// The hosts of the entries are kept and collapsed into one entry of the new secret, entries without hosts are rotated as well.
+func ApplyRotateTlsSecret(ingress *networking.Ingress, from string, to string) []TlsChange {
+	before := make([]networking.IngressTLS, len(ingress.Spec.TLS))
+	copy(before, ingress.Spec.TLS)
+
+	rotated := false
+	for i, tlsEntry := range ingress.Spec.TLS {
+		if tlsEntry.SecretName == from {
+			ingress.Spec.TLS[i].SecretName = to
+			rotated = true
+		}
+	}
+	if rotated {
+		// collapse the rotated entries with existing entries of the new secret
+		ingress.Spec.TLS = groupTls(ingress.Spec.TLS)
+	}
+
+	changes := tlsChanges(before, ingress.Spec.TLS)
+	for _, tlsEntry := range before {
+		if tlsEntry.SecretName == from && len(tlsEntry.Hosts) == 0 {
+			// entries without hosts are not part of the host changes, report the rotated secret
+			changes = append(changes, TlsChange{Secret: from, Removed: true}, TlsChange{Secret: to})
+		}
+	}
+	return changes
+}
+
+// UsesTlsSecret checks if a tls entry of the ingress references the secret.
+func UsesTlsSecret(ingress *networking.Ingress, secret string) bool {
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if tlsEntry.SecretName == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// TlsHostsOfSecret returns the hosts of all tls entries of the ingress referencing the secret.
+func TlsHostsOfSecret(ingress *networking.Ingress, secret string) []string {
+	var hosts []string
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if tlsEntry.SecretName == secret {
+			hosts = append(hosts, tlsEntry.Hosts...)
+		}
+	}
+	return hosts
+}
+
+// updateTls applies the tls modification to the ingress and updates it if the tls configuration changed.
+func (i *IngressService) updateTls(ctx context.Context, apply func(ingress *networking.Ingress) ([]TlsChange, error)) (changes []TlsChange, err error) {
+	err = i.retryOnConflict(ctx, func(ctx context.Context) error {
+		ingress, err := i.kubeIngress.Get(ctx, i.ingressName, meta.GetOptions{})
+		if err != nil {
+			return err
+		}
+		live := ingress.DeepCopy()
+
+		if changes, err = apply(ingress); err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return i.update(ctx, live, ingress)
+	})
+
+	return changes, err
+}
+
+// ReconcileTls aligns the tls configuration of the ingress with its rules.
+// Hosts which are no longer used by any rule are removed, a wildcard host is kept as long as it covers the host of a rule.
+// Hosts configured for more than one secret keep the first secret and entries with the same secret are collapsed into one entry.
+// Entries without hosts are preserved.
+// Returns the hosts added to or removed from the secrets
+func ReconcileTls(ingress *networking.Ingress) []TlsChange {
+	return reconcileTls(ingress, nil)
+}
+
+// reconcileTls reconciles the tls configuration like ReconcileTls, the secrets map configures the secret of hosts explicitly.
+// A host moved to another secret is split from its previous entry, a host mapped to an empty secret is removed.
+func reconcileTls(ingress *networking.Ingress, secrets map[string]string) []TlsChange {
+	ruleHosts := ruleHosts(ingress)
+
+	// compute the wanted secret of every tls host, the first entry of a host wins
+	wanted := map[string]string{}
+	var hosts []string
+	for _, tlsEntry := range ingress.Spec.TLS {
+		for _, host := range tlsEntry.Hosts {
+			if _, ok := wanted[host]; ok || !tlsHostUsed(host, ruleHosts) {
+				continue
+			}
+			wanted[host] = tlsEntry.SecretName
+			hosts = append(hosts, host)
+		}
+	}
+	for _, host := range sortedKeys(secrets) {
+		if secrets[host] == "" {
+			delete(wanted, host)
+			continue
+		}
+		if _, ok := wanted[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		wanted[host] = secrets[host]
+	}
+
+	// keep the hosts of the existing entries which have the wanted secret, hosts with another secret are appended
+	var newTlsConfig []networking.IngressTLS
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if len(tlsEntry.Hosts) == 0 {
+			// entry for the default certificate of the ingress controller, unrelated to the rules
+			newTlsConfig = append(newTlsConfig, tlsEntry)
+			continue
+		}
+		var entryHosts []string
+		for _, host := range tlsEntry.Hosts {
+			if secret, ok := wanted[host]; ok && secret == tlsEntry.SecretName {
+				entryHosts = append(entryHosts, host)
+			}
+		}
+		if len(entryHosts) > 0 {
+			newTlsConfig = append(newTlsConfig, networking.IngressTLS{Hosts: entryHosts, SecretName: tlsEntry.SecretName})
+		}
+	}
+	for _, host := range hosts {
+		if secret, ok := wanted[host]; ok {
+			newTlsConfig = append(newTlsConfig, networking.IngressTLS{Hosts: []string{host}, SecretName: secret})
+		}
+	}
+	newTlsConfig = groupTls(newTlsConfig)
+
+	changes := tlsChanges(ingress.Spec.TLS, newTlsConfig)
+	ingress.Spec.TLS = newTlsConfig
+	return changes
+}
+
+// ruleHosts returns the hosts of the rules, the rule without host is omitted.
+func ruleHosts(ingress *networking.Ingress) []string {
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+// tlsSecretOfHost returns the secret of the first tls entry containing the host.
+func tlsSecretOfHost(ingress *networking.Ingress, host string) (string, bool) {
+	for _, tlsEntry := range ingress.Spec.TLS {
+		for _, tlsHost := range tlsEntry.Hosts {
+			if tlsHost == host {
+				return tlsEntry.SecretName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// tlsEntryContains checks if a tls entry with the secret contains the host.
+func tlsEntryContains(ingress *networking.Ingress, host string, secret string) bool {
+	for _, tlsEntry := range ingress.Spec.TLS {
+		if tlsEntry.SecretName != secret {
+			continue
+		}
+		for _, tlsHost := range tlsEntry.Hosts {
+			if tlsHost == host {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tlsHostUsed checks if the tls host equals the host of a rule or covers it as wildcard.
+func tlsHostUsed(tlsHost string, ruleHosts []string) bool {
+	for _, host := range ruleHosts {
+		if HostCoveredBy(host, tlsHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostCoveredBy checks if the host is equal to the pattern or matched by the wildcard pattern.
+// A wildcard only matches a single label, "*.example.com" matches "foo.example.com" but neither "example.com" nor "foo.bar.example.com".
+func HostCoveredBy(host string, pattern string) bool {
+	host, pattern = strings.ToLower(host), strings.ToLower(pattern)
+	if host == pattern {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*.") || !strings.HasSuffix(host, pattern[1:]) {
+		return false
+	}
+	label := strings.TrimSuffix(host, pattern[1:])
+	return label != "" && label != "*" && !strings.Contains(label, ".")
+}
+
+// groupTls groups the hosts by secret in the order of the entries, hosts of later entries with the same secret are moved to the first one.
+// Duplicate hosts are dropped and entries without hosts are preserved.
+func groupTls(tlsConfig []networking.IngressTLS) []networking.IngressTLS {
+	var grouped []networking.IngressTLS
+	entryIndex := map[string]int{}
+	for _, tlsEntry := range tlsConfig {
+		if len(tlsEntry.Hosts) == 0 {
+			grouped = append(grouped, tlsEntry)
+			continue
+		}
+		i, ok := entryIndex[tlsEntry.SecretName]
+		if !ok {
+			i = len(grouped)
+			entryIndex[tlsEntry.SecretName] = i
+			grouped = append(grouped, networking.IngressTLS{SecretName: tlsEntry.SecretName})
+		}
+		for _, host := range tlsEntry.Hosts {
+			if !containsHost(grouped, entryIndex, tlsEntry.SecretName, host) {
+				grouped[i].Hosts = append(grouped[i].Hosts, host)
+			}
+		}
+	}
+	return grouped
+}
+
+func containsHost(tlsConfig []networking.IngressTLS, entryIndex map[string]int, secret string, host string) bool {
+	i, ok := entryIndex[secret]
+	if !ok {
+		return false
+	}
+	for _, existingHost := range tlsConfig[i].Hosts {
+		if existingHost == host {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsChanges compares the hosts of the secrets before and after the reconciliation.
+func tlsChanges(before []networking.IngressTLS, after []networking.IngressTLS) []TlsChange {
+	beforePairs, afterPairs := tlsPairs(before), tlsPairs(after)
+
+	var changes []TlsChange
+	for _, pair := range orderedTlsPairs(before) {
+		if !afterPairs[pair] {
+			changes = append(changes, TlsChange{Host: pair.Host, Secret: pair.Secret, Removed: true})
+		}
+	}
+	for _, pair := range orderedTlsPairs(after) {
+		if !beforePairs[pair] {
+			changes = append(changes, TlsChange{Host: pair.Host, Secret: pair.Secret})
+		}
+	}
+	return changes
+}
+
+func tlsPairs(tlsConfig []networking.IngressTLS) map[TlsChange]bool {
+	pairs := map[TlsChange]bool{}
+	for _, pair := range orderedTlsPairs(tlsConfig) {
+		pairs[pair] = true
+	}
+	return pairs
+}
+
+// orderedTlsPairs returns the distinct host and secret pairs of the tls configuration in their order of appearance.
+func orderedTlsPairs(tlsConfig []networking.IngressTLS) []TlsChange {
+	var pairs []TlsChange
+	seen := map[TlsChange]bool{}
+	for _, tlsEntry := range tlsConfig {
+		for _, host := range tlsEntry.Hosts {
+			pair := TlsChange{Host: host, Secret: tlsEntry.SecretName}
+			if !seen[pair] {
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var ErrTlsHostNotFound = errors.New("ingress has no rule for tls host")
+var ErrTlsConfigurationNotFound = errors.New("no tls configuration for host")
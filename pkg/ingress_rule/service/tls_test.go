@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/typed/networking/v1/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestReconcileTls(t *testing.T) {
+	tests := []struct {
+		name            string
+		ruleHosts       []string
+		existingTls     []networking.IngressTLS
+		secrets         map[string]string
+		expectedTls     []networking.IngressTLS
+		expectedChanges []TlsChange
+	}{
+		{
+			name:        "keep tls of existing hosts",
+			ruleHosts:   []string{"foo.com", "bar.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+		},
+		{
+			name:        "remove all stale hosts of an entry",
+			ruleHosts:   []string{"baz.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com", "baz.com"}, SecretName: "my-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"baz.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{
+				{Host: "foo.com", Secret: "my-secret", Removed: true},
+				{Host: "bar.com", Secret: "my-secret", Removed: true},
+			},
+		},
+		{
+			name:      "remove entries without remaining hosts",
+			ruleHosts: []string{"bar.com"},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "foo-secret"},
+				{Hosts: []string{"bar.com"}, SecretName: "bar-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"bar.com"}, SecretName: "bar-secret"}},
+			expectedChanges: []TlsChange{{Host: "foo.com", Secret: "foo-secret", Removed: true}},
+		},
+		{
+			name:      "preserve entries without hosts",
+			ruleHosts: []string{"bar.com"},
+			existingTls: []networking.IngressTLS{
+				{SecretName: "default-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "foo-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{SecretName: "default-secret"}},
+			expectedChanges: []TlsChange{{Host: "foo.com", Secret: "foo-secret", Removed: true}},
+		},
+		{
+			name:      "collapse entries with the same secret",
+			ruleHosts: []string{"foo.com", "bar.com", "baz.com"},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "my-secret"},
+				{Hosts: []string{"baz.com"}, SecretName: "baz-secret"},
+				{Hosts: []string{"bar.com", "foo.com"}, SecretName: "my-secret"},
+			},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"},
+				{Hosts: []string{"baz.com"}, SecretName: "baz-secret"},
+			},
+		},
+		{
+			name:      "first secret of a host wins",
+			ruleHosts: []string{"foo.com"},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "first-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "second-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "first-secret"}},
+			expectedChanges: []TlsChange{{Host: "foo.com", Secret: "second-secret", Removed: true}},
+		},
+		{
+			name:        "split host moved to another secret",
+			ruleHosts:   []string{"foo.com", "bar.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "old-secret"}},
+			secrets:     map[string]string{"foo.com": "new-secret"},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"bar.com"}, SecretName: "old-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "new-secret"},
+			},
+			expectedChanges: []TlsChange{
+				{Host: "foo.com", Secret: "old-secret", Removed: true},
+				{Host: "foo.com", Secret: "new-secret"},
+			},
+		},
+		{
+			name:        "join host to existing entry of the secret",
+			ruleHosts:   []string{"foo.com", "bar.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			secrets:     map[string]string{"bar.com": "my-secret"},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{
+				{Host: "bar.com", Secret: "my-secret"},
+			},
+		},
+		{
+			name:        "keep wildcard host covering a rule host",
+			ruleHosts:   []string{"foo.example.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-secret"}},
+		},
+		{
+			name:        "keep wildcard host of wildcard rule",
+			ruleHosts:   []string{"*.example.com"},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-secret"}},
+		},
+		{
+			name:      "remove wildcard host without covered rule host",
+			ruleHosts: []string{"example.com", "foo.bar.example.com"},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"*.example.com", "example.com"}, SecretName: "wildcard-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "wildcard-secret"}},
+			expectedChanges: []TlsChange{{Host: "*.example.com", Secret: "wildcard-secret", Removed: true}},
+		},
+		{
+			name:      "remove specific host when only the wildcard rule is left",
+			ruleHosts: []string{"*.example.com"},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.example.com", "*.example.com"}, SecretName: "my-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{{Host: "foo.example.com", Secret: "my-secret", Removed: true}},
+		},
+		{
+			name:        "no tls configuration",
+			ruleHosts:   []string{"foo.com"},
+			expectedTls: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{Spec: networking.IngressSpec{TLS: test.existingTls}}
+			for _, host := range test.ruleHosts {
+				ingress.Spec.Rules = append(ingress.Spec.Rules, networking.IngressRule{Host: host})
+			}
+
+			changes := reconcileTls(ingress, test.secrets)
+			assert.Equal(t, test.expectedTls, ingress.Spec.TLS)
+			assert.Equal(t, test.expectedChanges, changes)
+		})
+	}
+}
+
+func TestHostCoveredBy(t *testing.T) {
+	tests := []struct {
+		host     string
+		pattern  string
+		expected bool
+	}{
+		{"foo.com", "foo.com", true},
+		{"Foo.com", "foo.COM", true},
+		{"bar.com", "foo.com", false},
+		{"foo.example.com", "*.example.com", true},
+		{"*.example.com", "*.example.com", true},
+		{"example.com", "*.example.com", false},
+		{"foo.bar.example.com", "*.example.com", false},
+		{"fooexample.com", "*.example.com", false},
+		{"*.example.com", "foo.example.com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.host+" "+test.pattern, func(t *testing.T) {
+			assert.Equal(t, test.expected, HostCoveredBy(test.host, test.pattern))
+		})
+	}
+}
+
+func TestApplyTlsOperations(t *testing.T) {
+	tests := []struct {
+		name            string
+		apply           func(ingress *networking.Ingress) ([]TlsChange, error)
+		existingTls     []networking.IngressTLS
+		expectedTls     []networking.IngressTLS
+		expectedChanges []TlsChange
+		expectedError   error
+	}{
+		{
+			name: "add tls for host",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyAddTls(ingress, []string{"bar.com"}, "my-secret")
+			},
+			existingTls:     []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{{Host: "bar.com", Secret: "my-secret"}},
+		},
+		{
+			name: "add wildcard tls covering a rule host",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyAddTls(ingress, []string{"*.example.com"}, "wildcard-secret")
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-secret"}},
+			expectedChanges: []TlsChange{{Host: "*.example.com", Secret: "wildcard-secret"}},
+		},
+		{
+			name: "add existing tls changes nothing",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyAddTls(ingress, []string{"foo.com"}, "my-secret")
+			},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+		},
+		{
+			name: "add tls for host with another secret returns ErrTlsConfigurationAlreadyExists",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyAddTls(ingress, []string{"foo.com"}, "my-secret")
+			},
+			existingTls:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "other-secret"}},
+			expectedError: ErrTlsConfigurationAlreadyExists,
+		},
+		{
+			name: "add tls for host without rule returns ErrTlsHostNotFound",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyAddTls(ingress, []string{"baz.com"}, "my-secret")
+			},
+			expectedError: ErrTlsHostNotFound,
+		},
+		{
+			name: "remove host from every secret",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRemoveTls(ingress, []string{"foo.com"}, "")
+			},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com", "bar.com"}, SecretName: "my-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "other-secret"},
+			},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"bar.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{
+				{Host: "foo.com", Secret: "my-secret", Removed: true},
+				{Host: "foo.com", Secret: "other-secret", Removed: true},
+			},
+		},
+		{
+			name: "remove host only from the secret",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRemoveTls(ingress, []string{"foo.com"}, "other-secret")
+			},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "my-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "other-secret"},
+			},
+			expectedTls:     []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedChanges: []TlsChange{{Host: "foo.com", Secret: "other-secret", Removed: true}},
+		},
+		{
+			name: "remove host without tls returns ErrTlsConfigurationNotFound",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRemoveTls(ingress, []string{"bar.com"}, "")
+			},
+			existingTls:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "my-secret"}},
+			expectedError: ErrTlsConfigurationNotFound,
+		},
+		{
+			name: "rotate splits entry",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRotateTls(ingress, []string{"foo.com"}, "new-secret")
+			},
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "old-secret"}},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"bar.com"}, SecretName: "old-secret"},
+				{Hosts: []string{"foo.com"}, SecretName: "new-secret"},
+			},
+			expectedChanges: []TlsChange{
+				{Host: "foo.com", Secret: "old-secret", Removed: true},
+				{Host: "foo.com", Secret: "new-secret"},
+			},
+		},
+		{
+			name: "rotate merges entries",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRotateTls(ingress, []string{"bar.com"}, "foo-secret")
+			},
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "foo-secret"},
+				{Hosts: []string{"bar.com"}, SecretName: "bar-secret"},
+			},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"foo.com", "bar.com"}, SecretName: "foo-secret"}},
+			expectedChanges: []TlsChange{
+				{Host: "bar.com", Secret: "bar-secret", Removed: true},
+				{Host: "bar.com", Secret: "foo-secret"},
+			},
+		},
+		{
+			name: "rotate host without tls returns ErrTlsConfigurationNotFound",
+			apply: func(ingress *networking.Ingress) ([]TlsChange, error) {
+				return ApplyRotateTls(ingress, []string{"bar.com"}, "new-secret")
+			},
+			expectedError: ErrTlsConfigurationNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{Spec: networking.IngressSpec{
+				Rules: []networking.IngressRule{ruleHostFoo(), ruleHostBar(), {Host: "foo.example.com"}},
+				TLS:   test.existingTls,
+			}}
+
+			changes, err := test.apply(ingress)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedChanges, changes)
+			assert.Equal(t, test.expectedTls, ingress.Spec.TLS)
+		})
+	}
+}
+
+func TestIngressService_RotateTls(t *testing.T) {
+	existingIngress := &networking.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: networking.IngressSpec{
+			Rules: []networking.IngressRule{ruleHostFoo()},
+			TLS:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "old-secret"}},
+		},
+	}
+
+	var updated *networking.Ingress
+	updates := 0
+	f := clienttesting.Fake{}
+	f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		if updated != nil {
+			return true, updated.DeepCopy(), nil
+		}
+		return true, existingIngress.DeepCopy(), nil
+	})
+	f.AddReactor("update", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		updates++
+		updated = action.(clienttesting.UpdateAction).GetObject().(*networking.Ingress)
+		return true, updated, nil
+	})
+
+	ingressService := IngressService{
+		kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+		ingressName: "foo",
+	}
+
+	changes, err := ingressService.RotateTls(context.TODO(), []string{"foo.com"}, "new-secret")
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "new-secret"}}, updated.Spec.TLS)
+
+	// rotating again changes nothing and does not update the ingress
+	changes, err = ingressService.RotateTls(context.TODO(), []string{"foo.com"}, "new-secret")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, 1, updates)
+}
+
+func TestIngressService_CheckAddTls(t *testing.T) {
+	f := clienttesting.Fake{}
+	f.AddReactor("get", "ingresses", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &networking.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec: networking.IngressSpec{
+				Rules: []networking.IngressRule{ruleHostFoo(), ruleHostBar()},
+				TLS:   []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "other-secret"}},
+			},
+		}, nil
+	})
+	ingressService := IngressService{
+		kubeIngress: &fake.FakeIngresses{Fake: &fake.FakeNetworkingV1{Fake: &f}},
+		ingressName: "foo",
+	}
+
+	assert.NoError(t, ingressService.CheckAddTls(context.TODO(), []string{"bar.com"}, "my-secret"))
+	assert.ErrorIs(t, ingressService.CheckAddTls(context.TODO(), []string{"foo.com"}, "my-secret"), ErrTlsConfigurationAlreadyExists)
+	assert.ErrorIs(t, ingressService.CheckAddTls(context.TODO(), []string{"baz.com"}, "my-secret"), ErrTlsHostNotFound)
+	for _, action := range f.Actions() {
+		assert.Equal(t, "get", action.GetVerb())
+	}
+}
+
+func TestApplyRotateTlsSecret(t *testing.T) {
+	tests := []struct {
+		name            string
+		existingTls     []networking.IngressTLS
+		expectedTls     []networking.IngressTLS
+		expectedChanges []TlsChange
+	}{
+		{
+			name: "rotate secret of all entries referencing it",
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com", "bar.com"}, SecretName: "old-secret"},
+				{Hosts: []string{"baz.com"}, SecretName: "other-secret"},
+				{Hosts: []string{"*.example.com"}, SecretName: "old-secret"},
+			},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com", "bar.com", "*.example.com"}, SecretName: "new-secret"},
+				{Hosts: []string{"baz.com"}, SecretName: "other-secret"},
+			},
+			expectedChanges: []TlsChange{
+				{Host: "foo.com", Secret: "old-secret", Removed: true},
+				{Host: "bar.com", Secret: "old-secret", Removed: true},
+				{Host: "*.example.com", Secret: "old-secret", Removed: true},
+				{Host: "foo.com", Secret: "new-secret"},
+				{Host: "bar.com", Secret: "new-secret"},
+				{Host: "*.example.com", Secret: "new-secret"},
+			},
+		},
+		{
+			name: "collapse rotated entry with existing entry of the new secret",
+			existingTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "new-secret"},
+				{Hosts: []string{"bar.com", "foo.com"}, SecretName: "old-secret"},
+			},
+			expectedTls: []networking.IngressTLS{
+				{Hosts: []string{"foo.com", "bar.com"}, SecretName: "new-secret"},
+			},
+			expectedChanges: []TlsChange{
+				{Host: "bar.com", Secret: "old-secret", Removed: true},
+				{Host: "foo.com", Secret: "old-secret", Removed: true},
+				{Host: "bar.com", Secret: "new-secret"},
+			},
+		},
+		{
+			name:        "rotate secret of entry without hosts",
+			existingTls: []networking.IngressTLS{{SecretName: "old-secret"}},
+			expectedTls: []networking.IngressTLS{{SecretName: "new-secret"}},
+			expectedChanges: []TlsChange{
+				{Secret: "old-secret", Removed: true},
+				{Secret: "new-secret"},
+			},
+		},
+		{
+			name:        "ingress without the secret is unchanged",
+			existingTls: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "other-secret"}},
+			expectedTls: []networking.IngressTLS{{Hosts: []string{"foo.com"}, SecretName: "other-secret"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := &networking.Ingress{Spec: networking.IngressSpec{TLS: test.existingTls}}
+
+			changes := ApplyRotateTlsSecret(ingress, "old-secret", "new-secret")
+			assert.Equal(t, test.expectedChanges, changes)
+			assert.Equal(t, test.expectedTls, ingress.Spec.TLS)
+		})
+	}
+}
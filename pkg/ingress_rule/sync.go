@@ -0,0 +1,234 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	"io"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"os"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// RulesFile describes the desired rules of one or more ingresses.
+type RulesFile struct {
+	Ingresses []RulesFileIngress `json:"ingresses"`
+}
+
+type RulesFileIngress struct {
+	Name             string          `json:"name"`
+	Namespace        string          `json:"namespace,omitempty"`
+	IngressClassName string          `json:"ingressClassName,omitempty"`
+	Rules            []RulesFileRule `json:"rules"`
+}
+
+type RulesFileRule struct {
+	Host     string             `json:"host,omitempty"`
+	Path     string             `json:"path,omitempty"`
+	PathType string             `json:"pathType,omitempty"`
+	Service  string             `json:"service"`
+	Port     intstr.IntOrString `json:"port"`
+	Tls      string             `json:"tls,omitempty"`
+}
+
+// ReadRulesFile reads and validates a rules file, "-" reads the rules from stdin.
+func ReadRulesFile(filename string) (*RulesFile, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	rulesFile := &RulesFile{}
+	if err = yaml.UnmarshalStrict(data, rulesFile); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, ingress := range rulesFile.Ingresses {
+		if ingress.Name == "" {
+			return nil, fmt.Errorf("invalid rules file: ingress without name")
+		}
+		if names[ingress.Namespace+"/"+ingress.Name] {
+			return nil, fmt.Errorf("invalid rules file: ingress '%s' is listed more than once", ingress.Name)
+		}
+		names[ingress.Namespace+"/"+ingress.Name] = true
+
+		if _, err = ingress.desiredRules(); err != nil {
+			return nil, fmt.Errorf("invalid rules file: ingress '%s': %w", ingress.Name, err)
+		}
+	}
+
+	return rulesFile, nil
+}
+
+// desiredRules validates the rules and rejects rules which are listed more than once.
+func (i RulesFileIngress) desiredRules() ([]service.DesiredRule, error) {
+	var desired []service.DesiredRule
+	keys := map[string]bool{}
+
+	for _, rule := range i.Rules {
+		d, err := rule.desiredRule()
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("%s|%s|%s", d.Host, d.Path, d.PathType)
+		if keys[key] {
+			return nil, fmt.Errorf("host '%s' with path '%s' (path type: '%s') is listed more than once", d.Host, d.Path, d.PathType)
+		}
+		keys[key] = true
+
+		desired = append(desired, d)
+	}
+
+	return desired, nil
+}
+
+// desiredRule validates the rule and applies the defaults for path and path type.
+func (rule RulesFileRule) desiredRule() (service.DesiredRule, error) {
+	d := service.DesiredRule{
+		Host:        rule.Host,
+		Path:        rule.Path,
+		PathType:    networking.PathTypePrefix,
+		ServiceName: rule.Service,
+		TlsSecret:   rule.Tls,
+	}
+	if d.Path == "" {
+		d.Path = "/"
+	}
+	if rule.PathType != "" {
+		pathType, err := ParsePathType(rule.PathType)
+		if err != nil {
+			return service.DesiredRule{}, err
+		}
+		d.PathType = pathType
+	}
+
+	if d.Host != "" && !ValidHost(d.Host) {
+		return service.DesiredRule{}, fmt.Errorf("invalid host '%s'", d.Host)
+	}
+	if !strings.HasPrefix(d.Path, "/") {
+		return service.DesiredRule{}, fmt.Errorf("invalid path '%s'", d.Path)
+	}
+	if d.ServiceName == "" {
+		return service.DesiredRule{}, fmt.Errorf("no service name supplied for host '%s' with path '%s'", d.Host, d.Path)
+	}
+	if d.TlsSecret != "" && d.Host == "" {
+		return service.DesiredRule{}, fmt.Errorf("tls configuration requires a hostname")
+	}
+
+	port, err := parseServicePort(rule.Port)
+	if err != nil {
+		return service.DesiredRule{}, err
+	}
+	d.ServicePort = port
+
+	return d, nil
+}
+
+// parseServicePort interprets the port either as port number or as port name.
+func parseServicePort(port intstr.IntOrString) (networking.ServiceBackendPort, error) {
+	if port.Type == intstr.String {
+		if len(validation.IsValidPortName(port.StrVal)) != 0 {
+			return networking.ServiceBackendPort{}, fmt.Errorf("invalid port '%s'", port.StrVal)
+		}
+		return networking.ServiceBackendPort{Name: port.StrVal}, nil
+	}
+
+	if port.IntVal <= 0 || port.IntVal >= 1<<16 {
+		return networking.ServiceBackendPort{}, fmt.Errorf("invalid port '%d'", port.IntVal)
+	}
+	return networking.ServiceBackendPort{Number: port.IntVal}, nil
+}
+
+// hostPattern matches hostnames, wildcards are allowed.
+var hostPattern = regexp.MustCompile("^([a-zA-Z0-9-_\\*]+\\.)*[a-zA-Z0-9][a-zA-Z0-9-_]+\\.[a-zA-Z]{2,11}?$")
+
+// ValidHost checks that the host is a valid hostname, wildcards are allowed.
+// The command line, the rules file and the KRM function share it, so they accept the same hosts.
+func ValidHost(host string) bool {
+	return hostPattern.MatchString(host)
+}
+
+// ParsePathType parses the path type case-insensitively.
+func ParsePathType(pathType string) (networking.PathType, error) {
+	switch strings.ToLower(pathType) {
+	case "exact":
+		return networking.PathTypeExact, nil
+	case "prefix":
+		return networking.PathTypePrefix, nil
+	case "implementationspecific":
+		return networking.PathTypeImplementationSpecific, nil
+	default:
+		return "", fmt.Errorf("invalid path type '%s'", pathType)
+	}
+}
+
+// RunSync reconciles all ingresses listed in the rules file with a single request per ingress.
+func RunSync(ctx context.Context, configFlags *genericclioptions.ConfigFlags, rulesFile *RulesFile, options *SyncOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	defaultNamespace, err := existingNamespace(ctx, configFlags, clientset)
+	if err != nil {
+		return err
+	}
+
+	for _, ingress := range rulesFile.Ingresses {
+		namespace := ingress.Namespace
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+
+		// already validated by ReadRulesFile
+		desired, _ := ingress.desiredRules()
+
+		ingressService := service.NewIngressService(clientset, namespace, ingress.Name, ingress.IngressClassName)
+		options.configure(ingressService)
+
+		result, err := ingressService.Sync(ctx, desired, options.Prune)
+		if err != nil {
+			return fmt.Errorf("failed to sync ingress '%s': %w", ingress.Name, err)
+		}
+		printSyncResult(ingress.Name, result, options)
+	}
+
+	return nil
+}
+
+func printSyncResult(ingressName string, result service.SyncResult, options *SyncOptions) {
+	if result.Created {
+		fmt.Printf("Created ingress '%s'%s\n", ingressName, options.dryRunSuffix())
+	}
+	for _, key := range result.Added {
+		fmt.Printf("Added rule for %s to ingress '%s'%s\n", key, ingressName, options.dryRunSuffix())
+	}
+	for _, key := range result.Updated {
+		fmt.Printf("Updated backend of rule for %s in ingress '%s'%s\n", key, ingressName, options.dryRunSuffix())
+	}
+	for _, key := range result.Removed {
+		fmt.Printf("Removed rule for %s from ingress '%s'%s\n", key, ingressName, options.dryRunSuffix())
+	}
+	for _, change := range result.TlsChanges {
+		fmt.Printf("Updated tls of ingress '%s': %s%s\n", ingressName, change, options.dryRunSuffix())
+	}
+	if result.Deleted {
+		fmt.Printf("Deleted ingress '%s'%s\n", ingressName, options.dryRunSuffix())
+	}
+	if !result.Created && !result.Deleted && len(result.Added)+len(result.Updated)+len(result.Removed)+len(result.TlsChanges) == 0 {
+		fmt.Printf("Ingress '%s' is in sync\n", ingressName)
+	}
+}
@@ -0,0 +1,54 @@
+package ingress_rule
+
+import (
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"testing"
+)
+
+func TestRulesFileRule_desiredRule(t *testing.T) {
+	tests := []struct {
+		name             string
+		rule             RulesFileRule
+		expectedPathType networking.PathType
+		expectedError    bool
+	}{
+		{
+			name:             "path type defaults to prefix",
+			rule:             RulesFileRule{Host: "foo.com", Service: "foo", Port: intstr.FromInt(80)},
+			expectedPathType: networking.PathTypePrefix,
+		},
+		{
+			name:             "path type is case-insensitive",
+			rule:             RulesFileRule{Host: "foo.com", PathType: "exact", Service: "foo", Port: intstr.FromInt(80)},
+			expectedPathType: networking.PathTypeExact,
+		},
+		{
+			name:          "invalid path type",
+			rule:          RulesFileRule{Host: "foo.com", PathType: "regex", Service: "foo", Port: intstr.FromInt(80)},
+			expectedError: true,
+		},
+		{
+			name:             "wildcard host",
+			rule:             RulesFileRule{Host: "*.foo.com", Service: "foo", Port: intstr.FromInt(80)},
+			expectedPathType: networking.PathTypePrefix,
+		},
+		{
+			name:          "host without top level domain is rejected like on the command line",
+			rule:          RulesFileRule{Host: "foo", Service: "foo", Port: intstr.FromInt(80)},
+			expectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := test.rule.desiredRule()
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedPathType, rule.PathType)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ingress_rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/pragaonj/ingress-rule-updater/pkg/ingress_rule/service"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+)
+
+// RunTls adds, removes or rotates the tls secret of hosts of the ingress.
+// Without ingress name a rotation replaces the tls secret in all ingresses referencing it.
+func RunTls(ctx context.Context, configFlags *genericclioptions.ConfigFlags, options *TlsOptions) error {
+	clientset, err := newClientset(configFlags)
+	if err != nil {
+		return err
+	}
+
+	namespace := metav1.NamespaceAll
+	if !options.AllNamespaces {
+		if namespace, err = existingNamespace(ctx, configFlags, clientset); err != nil {
+			return err
+		}
+	}
+
+	if options.From != "" {
+		return rotateTlsSecret(ctx, clientset, namespace, options)
+	}
+
+	ingressService := service.NewIngressService(clientset, namespace, options.IngressName, "")
+	options.configure(ingressService)
+
+	if options.Action == TlsAdd && options.CertFile != "" {
+		// the secret may be used by other ingresses, it is only written if the tls configuration can be added
+		if err = ingressService.CheckAddTls(ctx, options.Hosts, options.Secret); err != nil {
+			return withConflictHint(err)
+		}
+		if err = createTlsSecretFromFiles(ctx, clientset, namespace, options.Secret, options.Hosts, &options.TlsSecretOptions, &options.UpdateOptions); err != nil {
+			return err
+		}
+	} else if options.Action != TlsRemove && !options.SkipTlsValidation {
+		if err = validateTlsSecret(ctx, clientset, namespace, options.Secret, options.Hosts); err != nil {
+			return err
+		}
+	}
+
+	var changes []service.TlsChange
+	switch options.Action {
+	case TlsAdd:
+		changes, err = ingressService.AddTls(ctx, options.Hosts, options.Secret)
+	case TlsRemove:
+		changes, err = ingressService.RemoveTls(ctx, options.Hosts, options.Secret)
+	case TlsRotate:
+		changes, err = ingressService.RotateTls(ctx, options.Hosts, options.Secret)
+	}
+	if err != nil {
+		return withConflictHint(err)
+	}
+
+	printTlsChanges(options.IngressName, changes, &options.UpdateOptions)
+	return nil
+}
+
+// rotateTlsSecret replaces the tls secret in all ingresses of the namespace matching the label selector.
+// Every ingress is updated on its own, failures are reported and do not stop the rotation of the other ingresses.
+func rotateTlsSecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, options *TlsOptions) error {
+	ingressList, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{LabelSelector: options.LabelSelector})
+	if err != nil {
+		return err
+	}
+
+	rotated, failed := 0, 0
+	for _, ingress := range ingressList.Items {
+		if !service.UsesTlsSecret(&ingress, options.From) {
+			continue
+		}
+
+		if !options.SkipTlsValidation {
+			err = validateTlsSecret(ctx, clientset, ingress.Namespace, options.To, service.TlsHostsOfSecret(&ingress, options.From))
+			if err != nil {
+				failed++
+				fmt.Printf("Failed to rotate tls secret of ingress '%s/%s': %s\n", ingress.Namespace, ingress.Name, err)
+				continue
+			}
+		}
+
+		ingressService := service.NewIngressService(clientset, ingress.Namespace, ingress.Name, "")
+		options.configure(ingressService)
+		changes, err := ingressService.RotateTlsSecret(ctx, options.From, options.To)
+		if err != nil {
+			failed++
+			fmt.Printf("Failed to rotate tls secret of ingress '%s/%s': %s\n", ingress.Namespace, ingress.Name, err)
+			continue
+		}
+		if len(changes) == 0 {
+			// the ingress has been modified concurrently and no longer references the secret
+			continue
+		}
+		rotated++
+		fmt.Printf("Rotated tls secret '%s' to '%s' in ingress '%s/%s' (hosts: %s)%s\n",
+			options.From, options.To, ingress.Namespace, ingress.Name, formatTlsHosts(changes, options.To), options.dryRunSuffix())
+	}
+
+	if rotated == 0 && failed == 0 {
+		fmt.Printf("Doing nothing: No ingress references tls secret '%s'\n", options.From)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to rotate tls secret of %d ingress(es)", failed)
+	}
+	return nil
+}
+
+// formatTlsHosts returns the hosts added to the secret for messages to the user.
+func formatTlsHosts(changes []service.TlsChange, secret string) string {
+	var hosts []string
+	for _, change := range changes {
+		if !change.Removed && change.Secret == secret {
+			hosts = append(hosts, valueOrDefault(change.Host, "<default>"))
+		}
+	}
+	return strings.Join(hosts, ", ")
+}
+
+func printTlsChanges(ingressName string, changes []service.TlsChange, options *UpdateOptions) {
+	if len(changes) == 0 {
+		fmt.Println("Doing nothing: Tls configuration is already up to date")
+		return
+	}
+	for _, change := range changes {
+		fmt.Printf("Updated tls of ingress '%s': %s%s\n", ingressName, change, options.dryRunSuffix())
+	}
+}